@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/chanzuckerberg/blessclient/pkg/awsclient"
+	cziClient "github.com/chanzuckerberg/blessclient/pkg/client"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagRoleARN = "role-arn"
+
+	// the only credential_process version the aws sdks understand
+	credentialProcessVersion = 1
+)
+
+func init() {
+	awsCredentialsCmd.Flags().String(flagRoleARN, "", "Assume this role instead of client_config.role_arn")
+	addLoginFlags(awsCredentialsCmd)
+	rootCmd.AddCommand(awsCredentialsCmd)
+}
+
+// credentialProcessOutput is what the aws sdks expect from a credential_process
+// https://docs.aws.amazon.com/cli/latest/userguide/cli-configure-sourcing-external.html
+type credentialProcessOutput struct {
+	Version         int
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      *time.Time `json:",omitempty"`
+}
+
+var awsCredentialsCmd = &cobra.Command{
+	Use:           "aws-credentials",
+	Annotations:   map[string]string{annotationLockMode: string(util.LockShared)},
+	Short:         "aws-credentials prints aws credentials for use as an aws cli credential_process",
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		roleARN, err := cmd.Flags().GetString(flagRoleARN)
+		if err != nil {
+			return errors.Wrap(err, "Missing role-arn flag")
+		}
+
+		config, err := config.FromFile(config.DefaultConfigFile)
+		if err != nil {
+			return err
+		}
+		err = checkClientVersion(config)
+		if err != nil {
+			return err
+		}
+		if roleARN == "" {
+			roleARN = config.ClientConfig.RoleARN
+		}
+
+		loginConfig, err := getLoginConfig(cmd, config)
+		if err != nil {
+			return err
+		}
+
+		awsClient, err := awsclient.New(cmd.Context(), config.AWS)
+		if err != nil {
+			return err
+		}
+
+		tokens := cziClient.InteractiveTokens(awsClient, roleARN, loginConfig)
+		creds, _, err := tokens.Credentials(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		return printAWSCredentials(cmd.Context(), os.Stdout, creds)
+	},
+}
+
+// printAWSCredentials resolves creds and writes them to w in credential_process format
+func printAWSCredentials(ctx context.Context, w io.Writer, creds aws.CredentialsProvider) error {
+	value, err := creds.Retrieve(ctx)
+	if err != nil {
+		return errors.Wrap(err, "could not get aws credentials")
+	}
+
+	output := &credentialProcessOutput{
+		Version:         credentialProcessVersion,
+		AccessKeyID:     value.AccessKeyID,
+		SecretAccessKey: value.SecretAccessKey,
+		SessionToken:    value.SessionToken,
+	}
+	if value.CanExpire {
+		expiration := value.Expires.UTC()
+		output.Expiration = &expiration
+	}
+
+	data, err := json.Marshal(output)
+	if err != nil {
+		return errors.Wrap(err, "could not json marshal aws credentials")
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return errors.Wrap(err, "could not print aws credentials to stdout")
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintAWSCredentials(t *testing.T) {
+	r := require.New(t)
+	expiry := time.Date(2030, 7, 20, 12, 18, 2, 0, time.UTC)
+
+	provider := aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		return aws.Credentials{
+			AccessKeyID:     "AKID",
+			SecretAccessKey: "secret",
+			SessionToken:    "session",
+			CanExpire:       true,
+			Expires:         expiry,
+		}, nil
+	})
+
+	b := bytes.NewBuffer(nil)
+	r.NoError(printAWSCredentials(context.Background(), b, provider))
+	r.Equal(
+		`{"Version":1,"AccessKeyId":"AKID","SecretAccessKey":"secret","SessionToken":"session","Expiration":"2030-07-20T12:18:02Z"}`+"\n",
+		b.String())
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"time"
+
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/doctor"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// ssh -V can hang on a broken install
+	sshVersionTimeout = 5 * time.Second
+)
+
+func init() {
+	doctorCmd.Flags().StringP(flagOutput, "o", outputText, "Output format, text or json")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+var doctorCmd = &cobra.Command{
+	Use:           "doctor",
+	Short:         "Check your environment for common problems",
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, err := cmd.Flags().GetString(flagOutput)
+		if err != nil {
+			return errors.Wrap(err, "Missing output flag")
+		}
+		if output != outputText && output != outputJSON {
+			return withExitCode(ExitCodeConfigInvalid, errors.Errorf("unknown output %s, expected text or json", output))
+		}
+
+		results := runChecks(cmd.Context())
+		if output == outputJSON {
+			err = json.NewEncoder(os.Stdout).Encode(map[string]interface{}{"checks": results})
+			if err != nil {
+				return errors.Wrap(err, "could not write json output")
+			}
+		} else {
+			printChecks(os.Stdout, results)
+		}
+
+		if failed := doctor.Failed(results); failed > 0 {
+			return errors.Errorf("%d of %d checks failed", failed, len(results))
+		}
+		return nil
+	},
+}
+
+// runChecks runs every check against this machine
+func runChecks(ctx context.Context) []*doctor.Result {
+	now := time.Now()
+	results := []*doctor.Result{}
+
+	agentResult, certs := doctor.CheckAgent(os.Getenv("SSH_AUTH_SOCK"))
+	results = append(results, agentResult)
+	results = append(results, checkSSHVersion(ctx))
+
+	configPath, err := config.GetOrCreateConfigPath(config.DefaultConfigFile)
+	var conf *config.Config
+	if err != nil {
+		results = append(results, &doctor.Result{
+			Name:    doctor.CheckNameConfig,
+			Status:  doctor.StatusFail,
+			Message: err.Error(),
+		})
+	} else {
+		var configResult *doctor.Result
+		configResult, conf = doctor.CheckConfig(configPath)
+		results = append(results, configResult)
+		results = append(results, checkLock(configPath))
+	}
+
+	results = append(results, checkSSHConfig(conf))
+	results = append(results, doctor.CheckClock(certs, now))
+	return results
+}
+
+func checkSSHVersion(ctx context.Context) *doctor.Result {
+	ctx, cancel := context.WithTimeout(ctx, sshVersionTimeout)
+	defer cancel()
+
+	// ssh -V prints to stderr
+	out, err := exec.CommandContext(ctx, "ssh", "-V").CombinedOutput() // #nosec
+	if err != nil {
+		return &doctor.Result{
+			Name:        doctor.CheckNameSSHVersion,
+			Status:      doctor.StatusWarn,
+			Message:     fmt.Sprintf("could not run ssh -V: %s", err),
+			Remediation: "Make sure an OpenSSH client is installed and on your PATH.",
+		}
+	}
+	return doctor.CheckSSHVersion(string(out))
+}
+
+func checkLock(configPath string) *doctor.Result {
+	lock, err := util.NewLock(configPath)
+	if err != nil {
+		return &doctor.Result{
+			Name:    doctor.CheckNameLock,
+			Status:  doctor.StatusWarn,
+			Message: err.Error(),
+		}
+	}
+	return doctor.CheckLock(lock)
+}
+
+func checkSSHConfig(conf *config.Config) *doctor.Result {
+	sshDir, err := homedir.Expand("~/.ssh")
+	if err != nil {
+		return &doctor.Result{
+			Name:    doctor.CheckNameSSHConfig,
+			Status:  doctor.StatusWarn,
+			Message: err.Error(),
+		}
+	}
+	return doctor.CheckSSHConfig(path.Join(sshDir, "config"), conf)
+}
+
+// printChecks prints results for humans
+func printChecks(w io.Writer, results []*doctor.Result) {
+	for _, result := range results {
+		fmt.Fprintf(w, "[%s] %s: %s\n", result.Status, result.Name, result.Message)
+		if result.Status != doctor.StatusPass && result.Remediation != "" {
+			fmt.Fprintf(w, "       %s\n", result.Remediation)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"github.com/chanzuckerberg/blessclient/pkg/bless"
+	cziClient "github.com/chanzuckerberg/blessclient/pkg/client"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/pkg/errors"
+)
+
+// Exit codes wrappers can react to
+const (
+	// ExitCodeError is any error without a more specific exit code
+	ExitCodeError = 1
+	// ExitCodeAuthFailed means we could not log in or assume the role to call the CA with
+	ExitCodeAuthFailed = 3
+	// ExitCodeCARejected means the CA refused to sign a certificate
+	ExitCodeCARejected = 4
+	// ExitCodeAgentUnavailable means we could not use the ssh agent, or the key file in headless mode
+	ExitCodeAgentUnavailable = 5
+	// ExitCodeConfigInvalid means the blessclient config or flags are unusable
+	ExitCodeConfigInvalid = 6
+	// ExitCodeUpgradeRequired means the config needs a newer blessclient
+	ExitCodeUpgradeRequired = 7
+	// ExitCodeLocked means another blessclient held the lock for longer than --lock-wait
+	ExitCodeLocked = 8
+)
+
+// exitError attaches an exit code to an error
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode makes err exit with code, nil stays nil
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+// ExitCode returns the exit code for an error returned by Execute
+func ExitCode(err error) int {
+	var exitErr *exitError
+	var authErr *cziClient.AuthError
+	var keyManagerErr *cziClient.KeyManagerError
+	var versionErr *config.ClientVersionError
+	var lockedErr *util.LockedError
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &exitErr):
+		return exitErr.code
+	case errors.As(err, &authErr):
+		return ExitCodeAuthFailed
+	case errors.As(err, &keyManagerErr):
+		return ExitCodeAgentUnavailable
+	case errors.As(err, &lockedErr):
+		return ExitCodeLocked
+	case errors.As(err, &versionErr):
+		return ExitCodeUpgradeRequired
+	case errors.Is(err, bless.ErrCAMisconfigured):
+		return ExitCodeConfigInvalid
+	case errors.Is(err, bless.ErrAccessDenied),
+		errors.Is(err, bless.ErrInvalidKey),
+		errors.Is(err, bless.ErrRejected):
+		return ExitCodeCARejected
+	default:
+		return ExitCodeError
+	}
+}
+
+// checkClientVersion makes sure this blessclient is new enough for conf.
+// A min_client_version we can't parse is a config error.
+func checkClientVersion(conf *config.Config) error {
+	err := conf.CheckClientVersion()
+	var versionErr *config.ClientVersionError
+	if err == nil || errors.As(err, &versionErr) {
+		return err
+	}
+	return withExitCode(ExitCodeConfigInvalid, err)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/chanzuckerberg/blessclient/pkg/bless"
+	cziClient "github.com/chanzuckerberg/blessclient/pkg/client"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExitCode(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(0, ExitCode(nil))
+	r.Equal(ExitCodeError, ExitCode(errors.New("oops")))
+	r.Equal(ExitCodeConfigInvalid, ExitCode(withExitCode(ExitCodeConfigInvalid, errors.New("bad config"))))
+	r.Equal(ExitCodeAgentUnavailable, ExitCode(errors.Wrap(withExitCode(ExitCodeAgentUnavailable, errors.New("no agent")), "run")))
+	r.Equal(ExitCodeAuthFailed, ExitCode(&cziClient.AuthError{Err: errors.New("login failed")}))
+	r.Equal(ExitCodeAgentUnavailable, ExitCode(&cziClient.KeyManagerError{Err: errors.New("could not list agent keys")}))
+	r.Equal(ExitCodeLocked, ExitCode(&util.LockedError{Path: "/tmp/.lock", Holder: &util.Holder{PID: 1}}))
+	r.Equal(ExitCodeUpgradeRequired, ExitCode(&config.ClientVersionError{Version: "1.0.0", MinVersion: "1.1.0"}))
+
+	// failover returns every region's error
+	denied := multierror.Append(nil, &bless.Error{Kind: bless.ErrAccessDenied, Type: "AccessDenied"})
+	r.Equal(ExitCodeCARejected, ExitCode(denied))
+	r.Equal(ExitCodeCARejected, ExitCode(&bless.Error{Kind: bless.ErrInvalidKey, Type: "InvalidKey"}))
+	r.Equal(ExitCodeError, ExitCode(&bless.Error{Kind: bless.ErrCAUnavailable, Type: "ServiceException"}))
+	r.Equal(ExitCodeConfigInvalid, ExitCode(&bless.Error{Kind: bless.ErrCAMisconfigured, Type: "ResourceNotFoundException"}))
+
+	r.Nil(withExitCode(ExitCodeConfigInvalid, nil))
+}
+
+func TestCheckClientVersion(t *testing.T) {
+	r := require.New(t)
+
+	conf := &config.Config{MinClientVersion: "not a version"}
+	err := checkClientVersion(conf)
+	r.Error(err)
+	r.Equal(ExitCodeConfigInvalid, ExitCode(err))
+
+	conf.MinClientVersion = ""
+	r.NoError(checkClientVersion(conf))
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
 	getter "github.com/hashicorp/go-getter"
 	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
@@ -23,6 +24,7 @@ func init() {
 
 var importConfigCmd = &cobra.Command{
 	Use:           "import-config",
+	Annotations:   map[string]string{annotationLockMode: string(util.LockExclusive)},
 	Short:         "Import a blessclient config from a remote source",
 	Args:          cobra.ExactArgs(1),
 	Long:          "This command fetches a config from a remote source and writes it to disk",
@@ -53,7 +55,15 @@ var importConfigCmd = &cobra.Command{
 			return err
 		}
 
-		return conf.Persist(config.DefaultConfigFile)
+		err = conf.Persist(config.DefaultConfigFile)
+		if err != nil {
+			return err
+		}
+		err = conf.CheckClientVersion()
+		if err != nil {
+			log.Warn(err)
+		}
+		return nil
 	},
 }
 
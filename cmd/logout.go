@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/chanzuckerberg/blessclient/pkg/awsclient"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/login"
+	cziSSH "github.com/chanzuckerberg/blessclient/pkg/ssh"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagAllProfiles = "all-profiles"
+)
+
+func init() {
+	logoutCmd.Flags().Bool(flagAllProfiles, false, "Log out of every blessclient config in the config directory")
+	rootCmd.AddCommand(logoutCmd)
+}
+
+var logoutCmd = &cobra.Command{
+	Use:           "logout",
+	Annotations:   map[string]string{annotationLockMode: string(util.LockExclusive)},
+	Short:         "logout revokes and removes cached oidc tokens and removes blessclient certificates from the ssh agent",
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		allProfiles, err := cmd.Flags().GetBool(flagAllProfiles)
+		if err != nil {
+			return errors.Wrap(err, "Missing all-profiles flag")
+		}
+
+		profiles := []string{config.DefaultConfigFile}
+		if allProfiles {
+			profiles, err = config.Profiles(config.DefaultConfigFile)
+			if err != nil {
+				return err
+			}
+		}
+
+		var errs *multierror.Error
+		// profiles can share an oidc client
+		loggedOut := map[string]bool{}
+		for _, profile := range profiles {
+			conf, err := config.FromFile(profile)
+			if err != nil {
+				if allProfiles {
+					// other yaml files might live here too
+					logrus.WithError(err).Debugf("skipping %s", profile)
+					continue
+				}
+				return err
+			}
+
+			loginConfig := login.Config{
+				ClientID:  conf.ClientConfig.OIDCClientID,
+				IssuerURL: conf.ClientConfig.OIDCIssuerURL,
+			}
+			err = awsclient.ForgetCredentials(conf.ClientConfig.RoleARN, conf.ClientConfig.OIDCIssuerURL)
+			if err != nil {
+				// there might not be a keyring to cache them in
+				logrus.WithError(err).Debug("could not forget cached aws credentials")
+			}
+
+			key := loginConfig.IssuerURL + " " + loginConfig.ClientID
+			if loggedOut[key] {
+				continue
+			}
+			loggedOut[key] = true
+
+			err = login.Logout(cmd.Context(), &loginConfig)
+			if err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			logrus.Infof("Logged out of %s", loginConfig.IssuerURL)
+		}
+
+		err = removeAgentCertificates()
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+		return errs.ErrorOrNil()
+	},
+}
+
+// removeAgentCertificates removes blessclient certificates from the ssh agent, if there is one
+func removeAgentCertificates() error {
+	authSock := os.Getenv("SSH_AUTH_SOCK")
+	if authSock == "" {
+		logrus.Debug("no ssh agent, no certificates to remove")
+		return nil
+	}
+
+	a, err := cziSSH.GetSSHAgent(authSock)
+	if err != nil {
+		return err
+	}
+	defer a.Close()
+
+	removed, err := cziSSH.NewAgentKeyManager(a).RemoveCertificates()
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Removed %d certificate(s) from the ssh agent", removed)
+	return nil
+}
@@ -1,7 +1,14 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
 	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/logging"
+	"github.com/chanzuckerberg/blessclient/pkg/login"
 	"github.com/chanzuckerberg/blessclient/pkg/util"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -9,11 +16,35 @@ import (
 )
 
 const (
-	flagVerbose = "verbose"
+	flagVerbose     = "verbose"
+	flagLogFormat   = "log-format"
+	flagLogLevel    = "log-level"
+	flagLogFile     = "log-file"
+	flagLoginMethod = "login-method"
+	flagLockWait    = "lock-wait"
 )
 
+// annotationLockMode is the cobra annotation commands set to a util.LockMode
+// to take the lock while they run. Commands without it don't lock.
+const annotationLockMode = "blessclient/lock-mode"
+
+// runLockWait is how long run waits for the lock by default,
+// long enough for another blessclient to finish a browser login
+const runLockWait = 5 * time.Minute
+
 func init() {
-	rootCmd.PersistentFlags().BoolP(flagVerbose, "v", false, "Use this to enable verbose mode")
+	rootCmd.PersistentFlags().BoolP(flagVerbose, "v", false, "Use this to enable verbose mode, same as --log-level debug")
+	rootCmd.PersistentFlags().String(flagLogFormat, logging.FormatText, "Log format, text or json")
+	rootCmd.PersistentFlags().String(flagLogLevel, log.InfoLevel.String(), "Log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().String(flagLogFile, config.DefaultLogFile, "Also write debug logs to this file, empty to disable")
+	rootCmd.PersistentFlags().Duration(
+		flagLockWait,
+		util.DefaultLockWait,
+		fmt.Sprintf("How long to wait for another blessclient to release the lock, run defaults to %s", runLockWait),
+	)
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return withExitCode(ExitCodeConfigInvalid, err)
+	})
 }
 
 var pidLock *util.Lock
@@ -21,6 +52,9 @@ var rootCmd = &cobra.Command{
 	Use:   "blessclient",
 	Short: "",
 	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		if pidLock == nil {
+			return nil
+		}
 		return errors.Wrap(pidLock.Unlock(), "Error releasing lock")
 	},
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
@@ -29,25 +63,135 @@ var rootCmd = &cobra.Command{
 		if err != nil {
 			return errors.Wrap(err, "Missing verbose flag")
 		}
+		logConfig, err := getLogConfig(cmd)
+		if err != nil {
+			return withExitCode(ExitCodeConfigInvalid, err)
+		}
 		if verbose {
-			log.SetLevel(log.DebugLevel)
+			logConfig.Level = log.DebugLevel.String()
+		}
+		err = logging.Setup(log.StandardLogger(), logConfig)
+		if err != nil {
+			return withExitCode(ExitCodeConfigInvalid, err)
 		}
 
 		// pid lock
+		pidLock = nil
+		mode := lockMode(cmd)
+		if mode == util.LockNone {
+			return nil
+		}
 		configPath, err := config.GetOrCreateConfigPath(config.DefaultConfigFile)
 		if err != nil {
 			return err
 		}
 
+		lockWait, err := getLockWait(cmd)
+		if err != nil {
+			return err
+		}
 		pidLock, err = util.NewLock(configPath)
 		if err != nil {
 			return err
 		}
-		return errors.Wrap(pidLock.Lock(), "Error acquiring lock")
+		pidLock.SetMode(mode)
+		pidLock.SetWait(lockWait)
+		return pidLock.Lock()
 	},
 }
 
 // Execute executes the command
 func Execute() error {
-	return rootCmd.Execute()
+	return execute(os.Stdout)
+}
+
+// execute runs the command, run --output json still prints a result to stdout
+// when it fails before it gets to run, eg on bad flags or a held lock
+func execute(stdout io.Writer) error {
+	runResultWritten = false
+	cmd, err := rootCmd.ExecuteC()
+	if err == nil || cmd != runCmd || runResultWritten {
+		return err
+	}
+	output, outputErr := cmd.Flags().GetString(flagOutput)
+	if outputErr != nil || output != outputJSON {
+		return err
+	}
+	printErr := printRunResult(stdout, nil, err)
+	if printErr != nil {
+		log.WithError(printErr).Debug("could not print the run result")
+	}
+	return err
+}
+
+// getLogConfig builds a logging config from flags
+func getLogConfig(cmd *cobra.Command) (*logging.Config, error) {
+	format, err := cmd.Flags().GetString(flagLogFormat)
+	if err != nil {
+		return nil, errors.Wrap(err, "Missing log-format flag")
+	}
+	level, err := cmd.Flags().GetString(flagLogLevel)
+	if err != nil {
+		return nil, errors.Wrap(err, "Missing log-level flag")
+	}
+	file, err := cmd.Flags().GetString(flagLogFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Missing log-file flag")
+	}
+	return &logging.Config{
+		Format: format,
+		Level:  level,
+		File:   file,
+	}, nil
+}
+
+// lockMode returns how cmd takes the lock
+func lockMode(cmd *cobra.Command) util.LockMode {
+	mode, ok := cmd.Annotations[annotationLockMode]
+	if !ok {
+		return util.LockNone
+	}
+	return util.LockMode(mode)
+}
+
+// getLockWait returns how long cmd waits for the pid lock
+func getLockWait(cmd *cobra.Command) (time.Duration, error) {
+	lockWait, err := cmd.Flags().GetDuration(flagLockWait)
+	if err != nil {
+		return 0, errors.Wrap(err, "Missing lock-wait flag")
+	}
+	if cmd == runCmd && !cmd.Flags().Changed(flagLockWait) {
+		return runLockWait, nil
+	}
+	return lockWait, nil
+}
+
+// addLoginFlags adds flags for commands that might need to log in
+func addLoginFlags(cmd *cobra.Command) {
+	cmd.Flags().String(
+		flagLoginMethod,
+		string(login.MethodAuto),
+		"How to log in: browser, device (print a url and code to use from another device) or auto (device if there is no display)",
+	)
+}
+
+// getLoginConfig builds a login config from flags and the blessclient config
+func getLoginConfig(cmd *cobra.Command, conf *config.Config) (*login.Config, error) {
+	method, err := cmd.Flags().GetString(flagLoginMethod)
+	if err != nil {
+		return nil, errors.Wrap(err, "Missing login-method flag")
+	}
+	loginMethod, err := login.ParseMethod(method)
+	if err != nil {
+		return nil, err
+	}
+
+	return &login.Config{
+		ClientID:     conf.ClientConfig.OIDCClientID,
+		IssuerURL:    conf.ClientConfig.OIDCIssuerURL,
+		Method:       loginMethod,
+		Scopes:       conf.ClientConfig.OIDCScopes,
+		Audience:     conf.ClientConfig.OIDCAudience,
+		RedirectPort: conf.ClientConfig.OIDCRedirectPort,
+	}, nil
 }
@@ -1,8 +1,13 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"testing"
 
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/login"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/require"
 )
@@ -20,3 +25,102 @@ func TestRootMissingVerbose(t *testing.T) {
 	r.NotNil(err)
 	r.Contains(err.Error(), "flag accessed but not defined: verbose")
 }
+
+func TestGetLoginConfig(t *testing.T) {
+	r := require.New(t)
+	cmd := &cobra.Command{}
+	addLoginFlags(cmd)
+	r.NoError(cmd.Flags().Set(flagLoginMethod, "device"))
+
+	conf := config.DefaultConfig()
+	conf.ClientConfig.OIDCClientID = "client-id"
+	conf.ClientConfig.OIDCIssuerURL = "https://issuer.example.com"
+	conf.ClientConfig.OIDCScopes = []string{"openid", "groups"}
+	conf.ClientConfig.OIDCAudience = "api://bless"
+	conf.ClientConfig.OIDCRedirectPort = 8250
+
+	loginConfig, err := getLoginConfig(cmd, conf)
+	r.NoError(err)
+	r.Equal(&login.Config{
+		ClientID:     "client-id",
+		IssuerURL:    "https://issuer.example.com",
+		Method:       login.MethodDevice,
+		Scopes:       []string{"openid", "groups"},
+		Audience:     "api://bless",
+		RedirectPort: 8250,
+	}, loginConfig)
+}
+
+func TestLockModes(t *testing.T) {
+	r := require.New(t)
+	expected := map[string]util.LockMode{
+		"run":             util.LockExclusive,
+		"logout":          util.LockExclusive,
+		"import-config":   util.LockExclusive,
+		"token":           util.LockShared,
+		"aws-credentials": util.LockShared,
+		"version":         util.LockNone,
+		"doctor":          util.LockNone,
+		"upgrade":         util.LockNone,
+	}
+	for name, mode := range expected {
+		cmd, _, err := rootCmd.Find([]string{name})
+		r.NoError(err)
+		r.Equal(mode, lockMode(cmd), name)
+	}
+
+	inspect, _, err := rootCmd.Find([]string{"token", "inspect"})
+	r.NoError(err)
+	r.Equal(util.LockShared, lockMode(inspect))
+}
+
+func TestGetLockWait(t *testing.T) {
+	r := require.New(t)
+	// merges the persistent flags from root
+	r.NoError(versionCmd.ParseFlags(nil))
+	r.NoError(runCmd.ParseFlags(nil))
+
+	lockWait, err := getLockWait(versionCmd)
+	r.NoError(err)
+	r.Equal(util.DefaultLockWait, lockWait)
+
+	lockWait, err = getLockWait(runCmd)
+	r.NoError(err)
+	r.Equal(runLockWait, lockWait)
+}
+
+func TestExecuteRunJSONBeforeRun(t *testing.T) {
+	r := require.New(t)
+	defer rootCmd.SetArgs(nil)
+	// nolint: errcheck
+	defer runCmd.Flags().Set(flagOutput, outputText)
+	// nolint: errcheck
+	defer rootCmd.PersistentFlags().Set(flagLogLevel, "info")
+
+	cases := map[string][]string{
+		"bad flag":      {"run", "--output", "json", "--no-such-flag"},
+		"bad log level": {"run", "--output", "json", "--log-level", "loud"},
+	}
+	for name, args := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			rootCmd.SetArgs(args)
+			buf := bytes.NewBuffer(nil)
+			err := execute(buf)
+			r.Error(err)
+			r.Equal(ExitCodeConfigInvalid, ExitCode(err))
+
+			result := &runResult{}
+			r.NoError(json.Unmarshal(buf.Bytes(), result))
+			r.Equal(runActionFailed, result.Action)
+			r.Equal(ExitCodeConfigInvalid, result.ExitCode)
+			r.Equal(err.Error(), result.Error)
+		})
+	}
+
+	// other commands and text output print nothing
+	rootCmd.SetArgs([]string{"version", "--no-such-flag"})
+	buf := bytes.NewBuffer(nil)
+	r.Error(execute(buf))
+	r.Empty(buf.String())
+}
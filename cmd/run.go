@@ -1,174 +1,247 @@
 package cmd
 
 import (
-	"context"
-	"crypto"
+	"encoding/json"
+	"io"
 	"os"
+	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/chanzuckerberg/blessclient/pkg/awsclient"
 	"github.com/chanzuckerberg/blessclient/pkg/bless"
+	cziClient "github.com/chanzuckerberg/blessclient/pkg/client"
 	"github.com/chanzuckerberg/blessclient/pkg/config"
 	cziSSH "github.com/chanzuckerberg/blessclient/pkg/ssh"
-	cziAWS "github.com/chanzuckerberg/go-misc/aws"
-	oidc "github.com/chanzuckerberg/go-misc/oidc_cli"
-	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
-	"github.com/hashicorp/go-multierror"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/chanzuckerberg/blessclient/pkg/webidentity"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
-	"golang.org/x/crypto/ssh"
 )
 
 const (
 	flagForce     = "force"
 	flagPrintCert = "print-cert"
+	flagHeadless  = "headless"
+	flagTokenFile = "token-file"
+	flagTokenEnv  = "token-env"
+	flagKeyFile   = "key-file"
+	flagOutput    = "output"
+
+	outputText = "text"
+	outputJSON = "json"
+
+	runActionReused = "reused"
+	runActionMinted = "minted"
+	runActionFailed = "failed"
+
+	defaultTokenEnv = "BLESSCLIENT_OIDC_TOKEN"
+	defaultKeyFile  = "~/.blessclient/id_ed25519"
+
+	regionHealthFile = config.DefaultRegionHealthFile
 )
 
+// runResultWritten is true once run printed its json result,
+// execute prints one for errors that happen before run gets to
+var runResultWritten bool
+
 func init() {
 	runCmd.Flags().BoolP(flagForce, "f", false, "Force certificate refresh")
 	runCmd.Flags().Bool(flagPrintCert, false, "Prints the SSH Certificate for debugging purposes")
+	runCmd.Flags().Bool(flagHeadless, false, "Never open a browser, read an oidc token from --token-file, --token-env or GitHub Actions instead")
+	runCmd.Flags().String(flagTokenFile, "", "In headless mode, read the oidc token from this file")
+	runCmd.Flags().String(flagTokenEnv, defaultTokenEnv, "In headless mode, read the oidc token from this environment variable")
+	runCmd.Flags().String(flagKeyFile, defaultKeyFile, "In headless mode, write the key and certificate (<key-file>-cert.pub) here instead of the ssh agent")
+	runCmd.Flags().StringP(flagOutput, "o", outputText, "Output format, text or json (prints a result object to stdout)")
+	addLoginFlags(runCmd)
 
 	rootCmd.AddCommand(runCmd)
 }
 
 var runCmd = &cobra.Command{
 	Use:           "run",
+	Annotations:   map[string]string{annotationLockMode: string(util.LockExclusive)},
 	Short:         "run requests a certificate",
 	SilenceErrors: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		force, err := cmd.Flags().GetBool(flagForce)
+		output, err := cmd.Flags().GetString(flagOutput)
 		if err != nil {
-			return errors.Wrap(err, "Missing force flag")
+			return errors.Wrap(err, "Missing output flag")
 		}
-		printCert, err := cmd.Flags().GetBool(flagPrintCert)
-		if err != nil {
-			return errors.Wrap(err, "Missing print-cert flag")
+		if output != outputText && output != outputJSON {
+			return withExitCode(ExitCodeConfigInvalid, errors.Errorf("unknown output %s, expected text or json", output))
 		}
 
-		config, err := config.FromFile(config.DefaultConfigFile)
-		if err != nil {
-			return err
+		result, err := run(cmd)
+		if output == outputJSON {
+			printErr := printRunResult(os.Stdout, result, err)
+			runResultWritten = true
+			if err == nil {
+				err = printErr
+			}
 		}
-
-		a, err := cziSSH.GetSSHAgent(os.Getenv("SSH_AUTH_SOCK"))
-		if err != nil {
-			return err
+		if err == nil {
+			// only once the result is out, ssh may be waiting on us
+			upgradeNotice(cmd)
 		}
-		defer a.Close()
-		manager := cziSSH.NewAgentKeyManager(a)
+		return err
+	},
+}
 
-		hasCert, err := manager.HasValidCertificate()
-		if err != nil {
-			return err
-		}
-		if !force && hasCert {
-			logrus.Debug("fresh cert, nothing to do")
-			return nil
-		}
+// run makes sure we have a valid certificate
+func run(cmd *cobra.Command) (*cziClient.Result, error) {
+	force, err := cmd.Flags().GetBool(flagForce)
+	if err != nil {
+		return nil, errors.Wrap(err, "Missing force flag")
+	}
+	if force && pidLock != nil && pidLock.Waited() {
+		// whoever we waited on just got a certificate, don't throw it away
+		logrus.Info("Waited for another blessclient, reusing its certificate instead of forcing a refresh")
+		force = false
+	}
+	printCert, err := cmd.Flags().GetBool(flagPrintCert)
+	if err != nil {
+		return nil, errors.Wrap(err, "Missing print-cert flag")
+	}
+	headless, err := cmd.Flags().GetBool(flagHeadless)
+	if err != nil {
+		return nil, errors.Wrap(err, "Missing headless flag")
+	}
+	tokenFile, err := cmd.Flags().GetString(flagTokenFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Missing token-file flag")
+	}
+	tokenEnv, err := cmd.Flags().GetString(flagTokenEnv)
+	if err != nil {
+		return nil, errors.Wrap(err, "Missing token-env flag")
+	}
+	keyFile, err := cmd.Flags().GetString(flagKeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "Missing key-file flag")
+	}
 
-		pub, priv, err := manager.GetKey()
-		if err != nil {
-			return err
-		}
+	config, err := config.FromFile(config.DefaultConfigFile)
+	if err != nil {
+		return nil, withExitCode(ExitCodeConfigInvalid, err)
+	}
+	_, err = config.ClientConfig.GetIdentity()
+	if err != nil {
+		return nil, withExitCode(ExitCodeConfigInvalid, err)
+	}
+	err = checkClientVersion(config)
+	if err != nil {
+		return nil, err
+	}
 
-		sess, err := session.NewSession()
-		if err != nil {
-			return errors.Wrap(err, "could not initialize AWS session")
-		}
+	manager, closeManager, err := getKeyManager(headless, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	defer closeManager()
 
-		stsSvc := sts.New(sess)
-
-		credsProvider, err := oidc.NewAwsOIDCCredsProvider(
-			cmd.Context(),
-			stsSvc,
-			&oidc.AwsOIDCCredsProviderConfig{
-				AWSRoleARN:    config.ClientConfig.RoleARN,
-				OIDCClientID:  config.ClientConfig.OIDCClientID,
-				OIDCIssuerURL: config.ClientConfig.OIDCIssuerURL,
-			},
-		)
-		if err != nil {
-			return err
-		}
+	awsClient, err := awsclient.New(cmd.Context(), config.AWS)
+	if err != nil {
+		return nil, withExitCode(ExitCodeConfigInvalid, err)
+	}
 
-		token, err := credsProvider.FetchOIDCToken(cmd.Context())
+	var tokens cziClient.TokenProvider
+	if headless {
+		tokens = cziClient.HeadlessTokens(awsClient, config, webidentity.Options{
+			TokenFile: tokenFile,
+			TokenEnv:  tokenEnv,
+		})
+	} else {
+		loginConfig, err := getLoginConfig(cmd, config)
 		if err != nil {
-			return err
+			return nil, withExitCode(ExitCodeConfigInvalid, err)
 		}
+		tokens = cziClient.InteractiveTokens(awsClient, config.ClientConfig.RoleARN, loginConfig)
+	}
+
+	runner := cziClient.NewRunner(
+		manager,
+		tokens,
+		cziClient.NewLambdaSigner(awsClient, config, regionHealthFile),
+		nil,
+	)
+	result, err := runner.Run(cmd.Context(), cziClient.RunOptions{Force: force})
+	if err != nil {
+		if hint := bless.Remediation(err); hint != "" {
+			logrus.Warn(hint)
+		}
+		return nil, err
+	}
 
-		cert, err := regionalGetCert(
-			cmd.Context(),
-			sess,
-			credsProvider.Credentials,
-			config,
-			token,
-			pub,
-		)
+	if printCert && result.Refreshed {
+		err = cziSSH.PrintCertificate(result.Certificate, os.Stderr)
 		if err != nil {
-			return err
+			logrus.WithError(err).Debug("Could not print cert. Ignoring error.")
 		}
+	}
+	return result, nil
+}
 
-		if printCert {
-			err = cziSSH.PrintCertificate(cert, os.Stderr)
-			if err != nil {
-				logrus.WithError(err).Debug("Could not print cert. Ignoring error.")
-			}
-		}
+// runDurations are in milliseconds
+type runDurations struct {
+	Credentials int64 `json:"credentials"`
+	Sign        int64 `json:"sign"`
+	Total       int64 `json:"total"`
+}
 
-		err = manager.WriteKey(priv, cert)
-		if err != nil {
-			return err
-		}
+// runResult is what run --output json prints
+type runResult struct {
+	// Action is reused, minted or failed
+	Action      string        `json:"action"`
+	KeyID       string        `json:"key_id,omitempty"`
+	Principals  []string      `json:"principals,omitempty"`
+	ValidAfter  *time.Time    `json:"valid_after,omitempty"`
+	ValidBefore *time.Time    `json:"valid_before,omitempty"`
+	Region      string        `json:"region,omitempty"`
+	DurationsMS *runDurations `json:"durations_ms,omitempty"`
+	Error       string        `json:"error,omitempty"`
+	ExitCode    int           `json:"exit_code,omitempty"`
+}
 
-		hasCert, err = manager.HasValidCertificate()
-		if err != nil {
-			return err
+// printRunResult writes the outcome of run to w as json
+func printRunResult(w io.Writer, result *cziClient.Result, runErr error) error {
+	out := runResult{}
+	switch {
+	case runErr != nil:
+		out.Action = runActionFailed
+		out.Error = runErr.Error()
+		out.ExitCode = ExitCode(runErr)
+	default:
+		out.Action = runActionReused
+		if result.Refreshed {
+			out.Action = runActionMinted
+		}
+		validAfter := result.ValidAfter.UTC()
+		validBefore := result.ValidBefore.UTC()
+		out.KeyID = result.Certificate.KeyId
+		out.Principals = result.Certificate.ValidPrincipals
+		out.ValidAfter = &validAfter
+		out.ValidBefore = &validBefore
+		out.Region = result.Region
+		out.DurationsMS = &runDurations{
+			Credentials: result.CredentialsDuration.Milliseconds(),
+			Sign:        result.SignDuration.Milliseconds(),
+			Total:       result.TotalDuration.Milliseconds(),
 		}
+	}
 
-		if !hasCert {
-			return errors.Errorf("wrote error to key manager, but could not fetch it back")
-		}
-		return nil
-	},
+	err := json.NewEncoder(w).Encode(out)
+	return errors.Wrap(err, "could not write json output")
 }
 
-func regionalGetCert(
-	ctx context.Context,
-	sess *session.Session,
-	creds *credentials.Credentials,
-	blessConfig *config.Config,
-	token *client.Token,
-	publicKey crypto.PublicKey,
-) (*ssh.Certificate, error) {
-	var errors *multierror.Error
-
-	for _, region := range blessConfig.LambdaConfig.Regions {
-		logrus.Debugf("Attempting to get cert from region %s", region.AWSRegion)
-
-		awsConf := aws.NewConfig().WithCredentials(creds).WithRegion(region.AWSRegion)
-		awsClient := cziAWS.New(sess).WithLambda(awsConf)
-		client := bless.NewOIDC(awsClient, &blessConfig.LambdaConfig)
-		cert, err := client.RequestCert(
-			ctx,
-			awsClient,
-			&bless.SigningRequest{
-				PublicKeyToSign: bless.NewPublicKeyToSign(publicKey),
-				Identity: bless.Identity{
-					OktaAccessToken: &bless.OktaAccessTokenInput{
-						AccessToken: token.AccessToken,
-					},
-				},
-			},
-		)
-		// if no error, done and return
-		if err == nil {
-			return cert, nil
-		}
-		// if error, accumulate it
-		errors = multierror.Append(errors, err)
+// getKeyManager returns the file key manager in headless mode and the ssh agent otherwise
+func getKeyManager(headless bool, keyFile string) (cziSSH.KeyManager, func(), error) {
+	if headless {
+		manager, err := cziSSH.NewFileKeyManager(keyFile)
+		return manager, func() {}, err
+	}
+
+	a, err := cziSSH.GetSSHAgent(os.Getenv("SSH_AUTH_SOCK"))
+	if err != nil {
+		return nil, nil, withExitCode(ExitCodeAgentUnavailable, err)
 	}
-	return nil, errors.ErrorOrNil()
+	return cziSSH.NewAgentKeyManager(a), func() { a.Close() }, nil
 }
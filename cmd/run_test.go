@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/chanzuckerberg/blessclient/pkg/bless"
+	cziClient "github.com/chanzuckerberg/blessclient/pkg/client"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestPrintRunResult(t *testing.T) {
+	r := require.New(t)
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	r.NoError(err)
+	sshPub, err := ssh.NewPublicKey(pub)
+	r.NoError(err)
+
+	validAfter := time.Date(2030, 7, 20, 12, 0, 0, 0, time.UTC)
+	validBefore := validAfter.Add(time.Hour)
+	result := &cziClient.Result{
+		Refreshed: true,
+		Certificate: &ssh.Certificate{
+			Key:             sshPub,
+			KeyId:           "user@example.com",
+			ValidPrincipals: []string{"user", "admin"},
+		},
+		ValidAfter:          validAfter,
+		ValidBefore:         validBefore,
+		Region:              "us-west-2",
+		CredentialsDuration: 120 * time.Millisecond,
+		SignDuration:        800 * time.Millisecond,
+		TotalDuration:       time.Second,
+	}
+
+	buf := bytes.NewBuffer(nil)
+	r.NoError(printRunResult(buf, result, nil))
+	r.JSONEq(`{
+		"action": "minted",
+		"key_id": "user@example.com",
+		"principals": ["user", "admin"],
+		"valid_after": "2030-07-20T12:00:00Z",
+		"valid_before": "2030-07-20T13:00:00Z",
+		"region": "us-west-2",
+		"durations_ms": {"credentials": 120, "sign": 800, "total": 1000}
+	}`, buf.String())
+
+	result.Refreshed = false
+	buf.Reset()
+	r.NoError(printRunResult(buf, result, nil))
+	r.Contains(buf.String(), `"action":"reused"`)
+}
+
+func TestPrintRunResultError(t *testing.T) {
+	r := require.New(t)
+
+	buf := bytes.NewBuffer(nil)
+	err := &bless.Error{Kind: bless.ErrAccessDenied, Type: "AccessDenied", Message: "not in group"}
+	r.NoError(printRunResult(buf, nil, err))
+	r.JSONEq(`{
+		"action": "failed",
+		"error": "bless error (access denied): AccessDenied: not in group",
+		"exit_code": 4
+	}`, buf.String())
+}
@@ -3,23 +3,60 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/chanzuckerberg/blessclient/pkg/config"
-	oidc "github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl"
+	"github.com/chanzuckerberg/blessclient/pkg/login"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+const (
+	flagFormat = "format"
+	flagMinTTL = "min-ttl"
+)
+
 func init() {
+	tokenCmd.Flags().String(
+		flagFormat,
+		tokenFormatJSON,
+		fmt.Sprintf("Output format, one of %s", strings.Join(tokenFormats, ", ")),
+	)
+	tokenCmd.Flags().Duration(flagMinTTL, 0, "Refresh the token if it expires sooner than this")
+	addLoginFlags(tokenCmd)
 	rootCmd.AddCommand(tokenCmd)
 }
 
 const (
 	stdoutTokenVersion = 1
+
+	tokenFormatJSON           = "json"
+	tokenFormatIDToken        = "id-token"
+	tokenFormatAccessToken    = "access-token"
+	tokenFormatHeader         = "header"
+	tokenFormatExport         = "export"
+	tokenFormatExecCredential = "exec-credential"
+
+	// kubectl tells exec plugins which ExecCredential version it wants here
+	envKubernetesExecInfo    = "KUBERNETES_EXEC_INFO"
+	defaultExecCredentialAPI = "client.authentication.k8s.io/v1"
 )
 
+var tokenFormats = []string{
+	tokenFormatJSON,
+	tokenFormatIDToken,
+	tokenFormatAccessToken,
+	tokenFormatHeader,
+	tokenFormatExport,
+	tokenFormatExecCredential,
+}
+
 type stdoutToken struct {
 	Version int `json:"version,omitempty"`
 
@@ -28,39 +65,118 @@ type stdoutToken struct {
 	Expiry      time.Time `json:"expiry,omitempty"`
 }
 
+// execCredential is the kubectl client-go credential plugin output
+// https://kubernetes.io/docs/reference/access-authn-authz/authentication/#client-go-credential-plugins
+type execCredential struct {
+	APIVersion string               `json:"apiVersion"`
+	Kind       string               `json:"kind"`
+	Status     execCredentialStatus `json:"status"`
+}
+
+type execCredentialStatus struct {
+	Token               string    `json:"token"`
+	ExpirationTimestamp time.Time `json:"expirationTimestamp"`
+}
+
 var tokenCmd = &cobra.Command{
 	Use:           "token",
+	Annotations:   map[string]string{annotationLockMode: string(util.LockShared)},
 	Short:         "token prints the oidc tokens to stdout",
 	SilenceErrors: true,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		stdoutToken := &stdoutToken{
-			Version: stdoutTokenVersion,
+		format, err := cmd.Flags().GetString(flagFormat)
+		if err != nil {
+			return errors.Wrap(err, "Missing format flag")
+		}
+		minTTL, err := cmd.Flags().GetDuration(flagMinTTL)
+		if err != nil {
+			return errors.Wrap(err, "Missing min-ttl flag")
+		}
+		// validate before we potentially send the user through a login
+		err = printToken(ioutil.Discard, format, &client.Token{})
+		if err != nil {
+			return err
 		}
 
 		config, err := config.FromFile(config.DefaultConfigFile)
 		if err != nil {
 			return err
 		}
+		err = checkClientVersion(config)
+		if err != nil {
+			return err
+		}
+
+		loginConfig, err := getLoginConfig(cmd, config)
+		if err != nil {
+			return err
+		}
+		loginConfig.MinTTL = minTTL
 
-		token, err := oidc.GetToken(
-			cmd.Context(),
-			config.ClientConfig.OIDCClientID,
-			config.ClientConfig.OIDCIssuerURL,
-		)
+		token, err := login.GetToken(cmd.Context(), loginConfig)
 		if err != nil {
 			return err
 		}
 
-		stdoutToken.AccessToken = token.AccessToken
-		stdoutToken.IDToken = token.IDToken
-		stdoutToken.Expiry = token.Expiry
+		return printToken(os.Stdout, format, token)
+	},
+}
 
-		data, err := json.Marshal(stdoutToken)
+// printToken writes token to w in format
+func printToken(w io.Writer, format string, token *client.Token) error {
+	var output string
+
+	switch format {
+	case tokenFormatJSON:
+		data, err := json.Marshal(&stdoutToken{
+			Version:     stdoutTokenVersion,
+			IDToken:     token.IDToken,
+			AccessToken: token.AccessToken,
+			Expiry:      token.Expiry,
+		})
 		if err != nil {
 			return errors.Wrap(err, "could not json marshal oidc token")
 		}
+		output = string(data)
+	case tokenFormatIDToken:
+		output = token.IDToken
+	case tokenFormatAccessToken:
+		output = token.AccessToken
+	case tokenFormatHeader:
+		output = fmt.Sprintf("Authorization: Bearer %s", token.AccessToken)
+	case tokenFormatExport:
+		output = strings.Join([]string{
+			fmt.Sprintf("export BLESSCLIENT_ID_TOKEN='%s'", token.IDToken),
+			fmt.Sprintf("export BLESSCLIENT_ACCESS_TOKEN='%s'", token.AccessToken),
+			fmt.Sprintf("export BLESSCLIENT_TOKEN_EXPIRY='%s'", token.Expiry.Format(time.RFC3339)),
+		}, "\n")
+	case tokenFormatExecCredential:
+		data, err := json.Marshal(&execCredential{
+			APIVersion: execCredentialAPIVersion(),
+			Kind:       "ExecCredential",
+			Status: execCredentialStatus{
+				Token:               token.IDToken,
+				ExpirationTimestamp: token.Expiry.UTC(),
+			},
+		})
+		if err != nil {
+			return errors.Wrap(err, "could not json marshal exec credential")
+		}
+		output = string(data)
+	default:
+		return errors.Errorf("unknown format %s, expected one of %s", format, strings.Join(tokenFormats, ", "))
+	}
 
-		_, err = fmt.Fprintln(os.Stdout, string(data))
-		return errors.Wrap(err, "could not print token to stdout")
-	},
+	_, err := fmt.Fprintln(w, output)
+	return errors.Wrap(err, "could not print token to stdout")
+}
+
+// execCredentialAPIVersion returns the ExecCredential version kubectl asked for
+func execCredentialAPIVersion() string {
+	execInfo := &execCredential{}
+	err := json.Unmarshal([]byte(os.Getenv(envKubernetesExecInfo)), execInfo)
+	if err != nil || execInfo.APIVersion == "" {
+		return defaultExecCredentialAPI
+	}
+	return execInfo.APIVersion
 }
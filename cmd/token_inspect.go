@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/jwt"
+	"github.com/chanzuckerberg/blessclient/pkg/login"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+const (
+	flagVerify   = "verify"
+	flagJWKSFile = "jwks-file"
+)
+
+func init() {
+	tokenInspectCmd.Flags().Bool(flagVerify, false, "Verify token signatures against the issuer's keys")
+	tokenInspectCmd.Flags().String(flagJWKSFile, "", "Verify against the JWKS in this file instead of fetching it from the issuer")
+	addLoginFlags(tokenInspectCmd)
+	tokenCmd.AddCommand(tokenInspectCmd)
+}
+
+var tokenInspectCmd = &cobra.Command{
+	Use:           "inspect",
+	Short:         "inspect decodes the oidc tokens and prints their header and claims",
+	SilenceErrors: true,
+	// without a token argument it gets one like token does
+	Annotations: map[string]string{annotationLockMode: string(util.LockShared)},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		verify, err := cmd.Flags().GetBool(flagVerify)
+		if err != nil {
+			return errors.Wrap(err, "Missing verify flag")
+		}
+		jwksFile, err := cmd.Flags().GetString(flagJWKSFile)
+		if err != nil {
+			return errors.Wrap(err, "Missing jwks-file flag")
+		}
+
+		config, err := config.FromFile(config.DefaultConfigFile)
+		if err != nil {
+			return err
+		}
+
+		loginConfig, err := getLoginConfig(cmd, config)
+		if err != nil {
+			return err
+		}
+
+		token, err := login.GetToken(cmd.Context(), loginConfig)
+		if err != nil {
+			return err
+		}
+
+		var keys *jose.JSONWebKeySet
+		switch {
+		case jwksFile != "":
+			keys, err = jwt.ReadJWKS(jwksFile)
+		case verify:
+			keys, err = jwt.FetchJWKS(cmd.Context(), nil, config.ClientConfig.OIDCIssuerURL)
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		err = inspectToken(os.Stdout, "id_token", token.IDToken, keys, now)
+		if err != nil {
+			return err
+		}
+		return inspectToken(os.Stdout, "access_token", token.AccessToken, keys, now)
+	},
+}
+
+// inspectToken prints the decoded token to w. If keys is not nil
+// we also verify the signature.
+func inspectToken(w io.Writer, name string, raw string, keys *jose.JSONWebKeySet, now time.Time) error {
+	fmt.Fprintf(w, "# %s\n", name)
+	if raw == "" {
+		fmt.Fprintln(w, "not present")
+		return nil
+	}
+
+	token, err := jwt.Decode(raw)
+	if err != nil {
+		// some issuers hand out opaque access tokens
+		fmt.Fprintf(w, "not a jwt: %s\n", err)
+		return nil
+	}
+
+	err = token.Print(w, now)
+	if err != nil {
+		return err
+	}
+
+	if keys == nil {
+		return nil
+	}
+	err = token.Verify(keys)
+	if err != nil {
+		return errors.Wrapf(err, "could not verify %s", name)
+	}
+	fmt.Fprintln(w, "signature: valid")
+	return nil
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectToken(t *testing.T) {
+	r := require.New(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	r.NoError(err)
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	r.NoError(err)
+	jws, err := signer.Sign([]byte(`{"sub":"user","exp":1577880000}`))
+	r.NoError(err)
+	raw, err := jws.CompactSerialize()
+	r.NoError(err)
+
+	now := time.Date(2020, 1, 1, 11, 0, 0, 0, time.UTC)
+	keys := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: key.Public()}}}
+
+	b := bytes.NewBuffer(nil)
+	r.NoError(inspectToken(b, "id_token", raw, keys, now))
+	r.Contains(b.String(), "sub: user")
+	r.Contains(b.String(), "2020-01-01T12:00:00Z (in 1h0m0s)")
+	r.Contains(b.String(), "signature: valid")
+
+	// opaque tokens are reported, not fatal
+	b.Reset()
+	r.NoError(inspectToken(b, "access_token", "opaque", keys, now))
+	r.Contains(b.String(), "not a jwt")
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	r.NoError(err)
+	wrongKeys := &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: other.Public()}}}
+	r.Error(inspectToken(b, "id_token", raw, wrongKeys, now))
+}
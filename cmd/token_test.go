@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintToken(t *testing.T) {
+	r := require.New(t)
+	expiry := time.Date(2020, 7, 20, 12, 18, 2, 0, time.UTC)
+	token := &client.Token{IDToken: "id", AccessToken: "access", Expiry: expiry}
+
+	cases := map[string]string{
+		tokenFormatJSON:        `{"version":1,"id_token":"id","access_token":"access","expiry":"2020-07-20T12:18:02Z"}` + "\n",
+		tokenFormatIDToken:     "id\n",
+		tokenFormatAccessToken: "access\n",
+		tokenFormatHeader:      "Authorization: Bearer access\n",
+		tokenFormatExport: "export BLESSCLIENT_ID_TOKEN='id'\n" +
+			"export BLESSCLIENT_ACCESS_TOKEN='access'\n" +
+			"export BLESSCLIENT_TOKEN_EXPIRY='2020-07-20T12:18:02Z'\n",
+	}
+
+	for format, expected := range cases {
+		b := bytes.NewBuffer(nil)
+		r.NoError(printToken(b, format, token))
+		r.Equal(expected, b.String(), format)
+	}
+
+	err := printToken(bytes.NewBuffer(nil), "yaml", token)
+	r.Error(err)
+	r.Contains(err.Error(), "unknown format yaml")
+}
+
+func TestPrintTokenExecCredential(t *testing.T) {
+	r := require.New(t)
+	expiry := time.Date(2020, 7, 20, 12, 18, 2, 0, time.UTC)
+	token := &client.Token{IDToken: "id", AccessToken: "access", Expiry: expiry}
+
+	b := bytes.NewBuffer(nil)
+	r.NoError(printToken(b, tokenFormatExecCredential, token))
+	r.JSONEq(`{
+		"apiVersion": "client.authentication.k8s.io/v1",
+		"kind": "ExecCredential",
+		"status": {"token": "id", "expirationTimestamp": "2020-07-20T12:18:02Z"}
+	}`, b.String())
+
+	// use whatever version kubectl asks for
+	t.Setenv("KUBERNETES_EXEC_INFO", `{"apiVersion": "client.authentication.k8s.io/v1beta1", "kind": "ExecCredential"}`)
+	b.Reset()
+	r.NoError(printToken(b, tokenFormatExecCredential, token))
+	cred := &execCredential{}
+	r.NoError(json.Unmarshal(b.Bytes(), cred))
+	r.Equal("client.authentication.k8s.io/v1beta1", cred.APIVersion)
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/upgrade"
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const (
+	flagManifest = "manifest"
+
+	// run should not wait on GitHub for long
+	upgradeNoticeTimeout = 2 * time.Second
+)
+
+func init() {
+	upgradeCmd.Flags().String(flagManifest, "", "Release manifest to upgrade from, defaults to upgrade.manifest_url in your config or GitHub releases")
+	rootCmd.AddCommand(upgradeCmd)
+}
+
+var upgradeCmd = &cobra.Command{
+	Use:           "upgrade",
+	Short:         "Upgrade blessclient to the latest release",
+	SilenceErrors: true,
+	// our releases aren't signed yet so this only works with releases you sign yourself,
+	// show it once there is a key in pkg/upgrade/release_keys
+	Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifest, err := cmd.Flags().GetString(flagManifest)
+		if err != nil {
+			return errors.Wrap(err, "Missing manifest flag")
+		}
+		upgradeConfig := getUpgradeConfig()
+		if manifest == "" {
+			manifest = upgradeConfig.ManifestURL
+		}
+
+		trustedKeys, err := upgrade.TrustedKeys(upgradeConfig.TrustedKeys)
+		if err != nil {
+			return withExitCode(ExitCodeConfigInvalid, err)
+		}
+		if len(trustedKeys) == 0 {
+			return withExitCode(ExitCodeConfigInvalid, errors.New("blessclient releases are not signed yet so they can't be upgraded in place, see https://github.com/chanzuckerberg/blessclient#install. To upgrade from releases you sign yourself add your key to upgrade.trusted_keys"))
+		}
+
+		current, err := upgrade.CurrentVersion()
+		if err != nil {
+			return err
+		}
+		release, err := upgrade.NewSource(manifest).Latest(cmd.Context())
+		if err != nil {
+			return err
+		}
+		if !upgrade.IsNewer(release, current) {
+			logrus.Infof("blessclient %s is up to date", current)
+			return nil
+		}
+
+		exe, err := upgrade.Executable()
+		if err != nil {
+			return err
+		}
+		logrus.Infof("Upgrading %s from %s to %s", exe, current, release.Version)
+		err = upgrade.Install(cmd.Context(), release, trustedKeys, exe)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("Upgraded to blessclient %s", release.Version)
+		return nil
+	},
+}
+
+// getUpgradeConfig returns the upgrade config, upgrading works without a blessclient config
+func getUpgradeConfig() *config.UpgradeConfig {
+	conf, err := config.FromFile(config.DefaultConfigFile)
+	if err != nil {
+		logrus.WithError(err).Debug("could not read config, upgrading from the defaults")
+		return &config.UpgradeConfig{}
+	}
+	if conf.Upgrade == nil {
+		return &config.UpgradeConfig{}
+	}
+	return conf.Upgrade
+}
+
+// upgradeNotice tells the user when a newer blessclient is available.
+// It is opt in and only for people at a terminal, since ssh runs us on every connection.
+func upgradeNotice(cmd *cobra.Command) {
+	headless, err := cmd.Flags().GetBool(flagHeadless)
+	if err != nil || headless || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return
+	}
+	upgradeConfig := getUpgradeConfig()
+	if !upgradeConfig.Notice {
+		return
+	}
+	// don't suggest an upgrade we can't verify
+	trustedKeys, err := upgrade.TrustedKeys(upgradeConfig.TrustedKeys)
+	if err != nil || len(trustedKeys) == 0 {
+		logrus.Debug("no trusted release keys, not checking for a newer blessclient")
+		return
+	}
+
+	cachePath, err := homedir.Expand(config.DefaultUpgradeCheckFile)
+	if err != nil {
+		logrus.WithError(err).Debug("could not check for a newer blessclient")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(cmd.Context(), upgradeNoticeTimeout)
+	defer cancel()
+	notice, err := upgrade.Notice(ctx, upgrade.NewSource(upgradeConfig.ManifestURL), cachePath, time.Now())
+	if err != nil {
+		logrus.WithError(err).Debug("could not check for a newer blessclient")
+		return
+	}
+	if notice != "" {
+		logrus.Info(notice)
+	}
+}
@@ -1,25 +1,90 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 
+	"github.com/chanzuckerberg/blessclient/pkg/upgrade"
 	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
+const (
+	flagJSON  = "json"
+	flagCheck = "check"
+)
+
 func init() {
+	versionCmd.Flags().Bool(flagJSON, false, "Print the version and build metadata as json")
+	versionCmd.Flags().Bool(flagCheck, false, "Compare with the latest published release")
 	rootCmd.AddCommand(versionCmd)
 }
 
+// versionInfo is what version --json prints
+type versionInfo struct {
+	*util.BuildInfo
+	// Latest and UpdateAvailable are only set with --check
+	Latest          string `json:"latest,omitempty"`
+	UpdateAvailable *bool  `json:"update_available,omitempty"`
+}
+
 var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version of blessclient",
 	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, err := cmd.Flags().GetBool(flagJSON)
+		if err != nil {
+			return errors.Wrap(err, "Missing json flag")
+		}
+		check, err := cmd.Flags().GetBool(flagCheck)
+		if err != nil {
+			return errors.Wrap(err, "Missing check flag")
+		}
+
 		v, err := util.VersionString()
 		if err != nil {
 			return err
 		}
-		fmt.Println(v)
+		buildInfo, err := util.GetBuildInfo()
+		if err != nil {
+			return err
+		}
+		info := &versionInfo{BuildInfo: buildInfo}
+
+		if check {
+			current, err := upgrade.CurrentVersion()
+			if err != nil {
+				return err
+			}
+			release, err := upgrade.NewSource(getUpgradeConfig().ManifestURL).Latest(cmd.Context())
+			if err != nil {
+				return err
+			}
+			updateAvailable := upgrade.IsNewer(release, current)
+			info.Latest = release.Version.String()
+			info.UpdateAvailable = &updateAvailable
+		}
+
+		if asJSON {
+			return errors.Wrap(json.NewEncoder(os.Stdout).Encode(info), "could not write json output")
+		}
+		printVersion(os.Stdout, v, info)
 		return nil
 	},
 }
+
+// printVersion prints the version for humans, the first line is only the version
+func printVersion(w io.Writer, version string, info *versionInfo) {
+	fmt.Fprintln(w, version)
+	if info.UpdateAvailable == nil {
+		return
+	}
+	if *info.UpdateAvailable {
+		fmt.Fprintf(w, "blessclient %s is available, see https://github.com/chanzuckerberg/blessclient#install\n", info.Latest)
+	} else {
+		fmt.Fprintf(w, "blessclient %s is the latest release\n", info.Latest)
+	}
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"testing"
 
 	"github.com/chanzuckerberg/blessclient/pkg/util"
@@ -9,7 +11,7 @@ import (
 
 func TestVersionNoError(t *testing.T) {
 	r := require.New(t)
-	err := versionCmd.RunE(nil, nil)
+	err := versionCmd.RunE(versionCmd, nil)
 	r.Nil(err)
 }
 
@@ -21,6 +23,39 @@ func TestVersionError(t *testing.T) {
 	}()
 	util.Release = "An Invalid Release"
 
-	err := versionCmd.RunE(nil, nil)
+	err := versionCmd.RunE(versionCmd, nil)
 	r.NotNil(err)
 }
+
+func TestPrintVersion(t *testing.T) {
+	r := require.New(t)
+	info := &versionInfo{BuildInfo: &util.BuildInfo{Version: "1.1.0"}}
+
+	buf := &bytes.Buffer{}
+	printVersion(buf, "1.1.0", info)
+	r.Equal("1.1.0\n", buf.String())
+
+	updateAvailable := true
+	info.Latest = "1.2.0"
+	info.UpdateAvailable = &updateAvailable
+	buf.Reset()
+	printVersion(buf, "1.1.0", info)
+	r.Equal("1.1.0\nblessclient 1.2.0 is available, see https://github.com/chanzuckerberg/blessclient#install\n", buf.String())
+}
+
+func TestVersionInfoJSON(t *testing.T) {
+	r := require.New(t)
+	buildInfo, err := util.GetBuildInfo()
+	r.NoError(err)
+
+	out, err := json.Marshal(&versionInfo{BuildInfo: buildInfo})
+	r.NoError(err)
+	fields := map[string]interface{}{}
+	r.NoError(json.Unmarshal(out, &fields))
+	for _, field := range []string{"version", "git_sha", "release", "dirty", "go_version", "os", "arch", "build_date"} {
+		r.Contains(fields, field)
+	}
+	// only with --check
+	r.NotContains(fields, "latest")
+	r.NotContains(fields, "update_available")
+}
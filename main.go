@@ -1,12 +1,15 @@
 package main
 
 import (
+	"os"
+
 	"github.com/chanzuckerberg/blessclient/cmd"
 	"github.com/sirupsen/logrus"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
-		logrus.Fatal(err)
+		logrus.Error(err)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
@@ -0,0 +1,133 @@
+package awsclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	// keyring service we store credentials under
+	keyringService = "blessclient"
+	// stop using cached credentials this long before they expire
+	defaultExpiryWindow = 5 * time.Minute
+
+	// CachedSource is the Source of credentials we read from the cache
+	CachedSource = "BlessclientCache"
+)
+
+// cachedCredentials is what we store in the keyring
+type cachedCredentials struct {
+	AccessKeyID     string    `json:"access_key_id"`
+	SecretAccessKey string    `json:"secret_access_key"`
+	SessionToken    string    `json:"session_token"`
+	Expiration      time.Time `json:"expiration"`
+}
+
+// CachedProvider caches the credentials of an expiring provider, such as an
+// assumed role, in the OS keyring so other blessclient invocations can reuse them.
+type CachedProvider struct {
+	provider aws.CredentialsProvider
+	key      string
+	now      func() time.Time
+}
+
+// NewCachedProvider caches the credentials provider gets for roleARN with a token from issuerURL
+func NewCachedProvider(provider aws.CredentialsProvider, roleARN string, issuerURL string) *CachedProvider {
+	return &CachedProvider{
+		provider: provider,
+		key:      cacheKey(roleARN, issuerURL),
+		now:      time.Now,
+	}
+}
+
+// NewCachedCredentials caches the credentials in memory and in the OS keyring
+func NewCachedCredentials(provider aws.CredentialsProvider, roleARN string, issuerURL string) *aws.CredentialsCache {
+	return aws.NewCredentialsCache(
+		NewCachedProvider(provider, roleARN, issuerURL),
+		func(o *aws.CredentialsCacheOptions) { o.ExpiryWindow = defaultExpiryWindow },
+	)
+}
+
+func cacheKey(roleARN string, issuerURL string) string {
+	return fmt.Sprintf("sts %s %s", roleARN, issuerURL)
+}
+
+// Retrieve returns the cached credentials if they are fresh,
+// otherwise it gets new ones and caches them.
+func (c *CachedProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	cached, err := c.read()
+	if err != nil {
+		// the cache is an optimization, never fail because of it
+		logrus.WithError(err).Debug("could not read cached aws credentials")
+	}
+	if cached != nil && cached.Expiration.After(c.now().Add(defaultExpiryWindow)) {
+		logrus.Debugf("reusing cached aws credentials until %s", cached.Expiration)
+		return aws.Credentials{
+			AccessKeyID:     cached.AccessKeyID,
+			SecretAccessKey: cached.SecretAccessKey,
+			SessionToken:    cached.SessionToken,
+			Source:          CachedSource,
+			CanExpire:       true,
+			Expires:         cached.Expiration,
+		}, nil
+	}
+
+	creds, err := c.provider.Retrieve(ctx)
+	if err != nil {
+		return creds, err
+	}
+	if !creds.CanExpire {
+		// these don't need caching
+		return creds, nil
+	}
+
+	err = c.write(&cachedCredentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Expiration:      creds.Expires,
+	})
+	if err != nil {
+		logrus.WithError(err).Debug("could not cache aws credentials")
+	}
+	return creds, nil
+}
+
+func (c *CachedProvider) read() (*cachedCredentials, error) {
+	data, err := keyring.Get(keyringService, c.key)
+	if err == keyring.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read from keyring")
+	}
+
+	cached := &cachedCredentials{}
+	err = json.Unmarshal([]byte(data), cached)
+	return cached, errors.Wrap(err, "could not json unmarshal cached aws credentials")
+}
+
+func (c *CachedProvider) write(cached *cachedCredentials) error {
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return errors.Wrap(err, "could not json marshal aws credentials")
+	}
+	return errors.Wrap(keyring.Set(keyringService, c.key, string(data)), "could not write to keyring")
+}
+
+// ForgetCredentials removes the cached credentials for roleARN and issuerURL,
+// for example because they were denied.
+func ForgetCredentials(roleARN string, issuerURL string) error {
+	err := keyring.Delete(keyringService, cacheKey(roleARN, issuerURL))
+	if err == keyring.ErrNotFound {
+		return nil
+	}
+	return errors.Wrap(err, "could not delete cached aws credentials from keyring")
+}
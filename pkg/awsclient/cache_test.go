@@ -0,0 +1,92 @@
+package awsclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+// countingProvider hands out credentials valid for validFor and counts calls
+type countingProvider struct {
+	validFor time.Duration
+	calls    int
+}
+
+func (p *countingProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	p.calls++
+	return aws.Credentials{
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		SessionToken:    "session",
+		Source:          "counting",
+		CanExpire:       true,
+		Expires:         time.Now().Add(p.validFor),
+	}, nil
+}
+
+func TestCachedProvider(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	keyring.MockInit()
+
+	provider := &countingProvider{validFor: time.Hour}
+	value, err := NewCachedProvider(provider, "arn:aws:iam::123456789012:role/bless", "https://issuer").Retrieve(ctx)
+	r.NoError(err)
+	r.Equal("counting", value.Source)
+	r.Equal(1, provider.calls)
+
+	// another invocation reuses them
+	cached := NewCachedProvider(provider, "arn:aws:iam::123456789012:role/bless", "https://issuer")
+	value, err = cached.Retrieve(ctx)
+	r.NoError(err)
+	r.Equal(CachedSource, value.Source)
+	r.Equal("session", value.SessionToken)
+	r.Equal(1, provider.calls)
+	r.True(value.Expires.After(time.Now()))
+
+	// but not for another role
+	_, err = NewCachedProvider(provider, "arn:aws:iam::123456789012:role/other", "https://issuer").Retrieve(ctx)
+	r.NoError(err)
+	r.Equal(2, provider.calls)
+
+	// forgotten credentials are fetched again
+	r.NoError(ForgetCredentials("arn:aws:iam::123456789012:role/bless", "https://issuer"))
+	_, err = NewCachedProvider(provider, "arn:aws:iam::123456789012:role/bless", "https://issuer").Retrieve(ctx)
+	r.NoError(err)
+	r.Equal(3, provider.calls)
+}
+
+func TestCachedProviderNearExpiry(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	keyring.MockInit()
+
+	provider := &countingProvider{validFor: time.Hour}
+	_, err := NewCachedProvider(provider, "role", "https://issuer").Retrieve(ctx)
+	r.NoError(err)
+
+	// close to expiring, we get new ones
+	cached := NewCachedProvider(provider, "role", "https://issuer")
+	cached.now = func() time.Time { return time.Now().Add(time.Hour - time.Minute) }
+	value, err := cached.Retrieve(ctx)
+	r.NoError(err)
+	r.Equal("counting", value.Source)
+	r.Equal(2, provider.calls)
+}
+
+func TestCachedProviderKeyringUnavailable(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	keyring.MockInitWithError(keyring.ErrUnsupportedPlatform)
+
+	provider := &countingProvider{validFor: time.Hour}
+	_, err := NewCachedProvider(provider, "role", "https://issuer").Retrieve(ctx)
+	r.NoError(err)
+	_, err = NewCachedProvider(provider, "role", "https://issuer").Retrieve(ctx)
+	r.NoError(err)
+	r.Equal(2, provider.calls)
+}
@@ -0,0 +1,270 @@
+// Package awsclient is the small slice of aws that blessclient uses,
+// behind an interface so it can be mocked.
+package awsclient
+
+import (
+	"context"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+)
+
+// default region of each partition, used for sts when no region is configured
+var partitionRegions = map[string]string{
+	"aws":        "us-east-1",
+	"aws-cn":     "cn-north-1",
+	"aws-us-gov": "us-gov-west-1",
+	"aws-iso":    "us-iso-east-1",
+	"aws-iso-b":  "us-isob-east-1",
+}
+
+// region prefixes of every partition but aws, which has everything else
+var partitionPrefixes = map[string]string{
+	"aws-cn":     "cn-",
+	"aws-us-gov": "us-gov-",
+	"aws-iso":    "us-iso-",
+	"aws-iso-b":  "us-isob-",
+}
+
+// ErrRegionNotInPartition means a region is outside the configured partition
+var ErrRegionNotInPartition = errors.New("region not in partition")
+
+// regionPartition returns the partition region is in
+func regionPartition(region string) string {
+	for partition, prefix := range partitionPrefixes {
+		if strings.HasPrefix(region, prefix) {
+			return partition
+		}
+	}
+	return "aws"
+}
+
+// checkPartition makes sure region is in partition, any region is fine without a partition
+func checkPartition(partition string, region string) error {
+	if partition == "" || regionPartition(region) == partition {
+		return nil
+	}
+	return errors.Wrapf(ErrRegionNotInPartition, "region %s is not in partition %s", region, partition)
+}
+
+//go:generate mockgen -source=client.go -destination=mocks/client.go -package=mocks
+
+// Client is what blessclient needs from aws
+type Client interface {
+	// AssumeRoleWithWebIdentity exchanges an oidc token for role credentials
+	AssumeRoleWithWebIdentity(ctx context.Context, input *AssumeRoleWithWebIdentityInput) (aws.Credentials, error)
+	// InvokeWithQualifier synchronously invokes a lambda function and returns its payload
+	InvokeWithQualifier(ctx context.Context, input *InvokeInput) ([]byte, error)
+	// PresignGetCallerIdentity presigns an sts:GetCallerIdentity request with the default credentials
+	PresignGetCallerIdentity(ctx context.Context) (*v4.PresignedHTTPRequest, error)
+}
+
+// AssumeRoleWithWebIdentityInput is the input to AssumeRoleWithWebIdentity
+type AssumeRoleWithWebIdentityInput struct {
+	RoleARN          string
+	RoleSessionName  string
+	WebIdentityToken string
+}
+
+// InvokeInput is the input to InvokeWithQualifier
+type InvokeInput struct {
+	Region       string
+	FunctionName string
+	// Qualifier is the function version or alias, nil means $LATEST
+	Qualifier *string
+	Payload   []byte
+	// Credentials to invoke the function with
+	Credentials aws.CredentialsProvider
+}
+
+// SDK implements Client with aws-sdk-go-v2
+type SDK struct {
+	sts    *sts.Client
+	lambda *lambda.Client
+
+	partition       string
+	lambdaEndpoints map[string]string
+}
+
+// New returns an SDK configured by awsConfig. A nil awsConfig means sdk defaults.
+func New(ctx context.Context, awsConfig *config.AWSConfig) (*SDK, error) {
+	if awsConfig == nil {
+		awsConfig = &config.AWSConfig{}
+	}
+
+	httpClient, err := newHTTPClient(awsConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewWithHTTPClient(ctx, awsConfig, httpClient)
+}
+
+// NewWithHTTPClient returns a new SDK that sends every request through httpClient.
+// It ignores the proxy and ca bundle in awsConfig.
+func NewWithHTTPClient(ctx context.Context, awsConfig *config.AWSConfig, httpClient aws.HTTPClient) (*SDK, error) {
+	if awsConfig == nil {
+		awsConfig = &config.AWSConfig{}
+	}
+
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithHTTPClient(httpClient),
+		awsconfig.WithRetryer(func() aws.Retryer { return retry.NewStandard() }),
+	}
+	if awsConfig.Profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(awsConfig.Profile))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not load aws config")
+	}
+
+	if awsConfig.Partition != "" {
+		region, ok := partitionRegions[awsConfig.Partition]
+		if !ok {
+			return nil, errors.Errorf("unknown aws partition %s", awsConfig.Partition)
+		}
+		if cfg.Region == "" {
+			cfg.Region = region
+		}
+	}
+	if cfg.Region == "" {
+		cfg.Region = partitionRegions["aws"]
+	}
+	err = checkPartition(awsConfig.Partition, cfg.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SDK{
+		sts: sts.NewFromConfig(cfg, func(o *sts.Options) {
+			if awsConfig.STSEndpoint != "" {
+				o.BaseEndpoint = aws.String(awsConfig.STSEndpoint)
+			}
+		}),
+		lambda: lambda.NewFromConfig(cfg, func(o *lambda.Options) {
+			// Failover already moves on to the next region when one is down, retrying there
+			// first would only delay it. Throttling is the exception, backing off is the fix.
+			o.Retryer = retry.NewStandard(func(so *retry.StandardOptions) {
+				so.Retryables = []retry.IsErrorRetryable{
+					retry.NoRetryCanceledError{},
+					retry.RetryableErrorCode{Codes: retry.DefaultThrottleErrorCodes},
+				}
+			})
+		}),
+		partition:       awsConfig.Partition,
+		lambdaEndpoints: awsConfig.LambdaEndpoints,
+	}, nil
+}
+
+func newHTTPClient(awsConfig *config.AWSConfig) (*awshttp.BuildableClient, error) {
+	var proxyURL *url.URL
+	if awsConfig.ProxyURL != "" {
+		var err error
+		proxyURL, err = url.Parse(awsConfig.ProxyURL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse proxy url %s", awsConfig.ProxyURL)
+		}
+	}
+
+	var rootCAs *x509.CertPool
+	if awsConfig.CABundle != "" {
+		caBundlePath, err := homedir.Expand(awsConfig.CABundle)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not expand %s", awsConfig.CABundle)
+		}
+		pem, err := ioutil.ReadFile(caBundlePath) // #nosec
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read ca bundle %s", awsConfig.CABundle)
+		}
+		rootCAs, err = x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in ca bundle %s", awsConfig.CABundle)
+		}
+	}
+
+	return awshttp.NewBuildableClient().WithTransportOptions(func(t *http.Transport) {
+		if proxyURL != nil {
+			t.Proxy = http.ProxyURL(proxyURL)
+		}
+		if rootCAs != nil {
+			t.TLSClientConfig.RootCAs = rootCAs
+		}
+	}), nil
+}
+
+// AssumeRoleWithWebIdentity exchanges an oidc token for role credentials
+func (s *SDK) AssumeRoleWithWebIdentity(ctx context.Context, input *AssumeRoleWithWebIdentityInput) (aws.Credentials, error) {
+	output, err := s.sts.AssumeRoleWithWebIdentity(ctx, &sts.AssumeRoleWithWebIdentityInput{
+		RoleArn:          aws.String(input.RoleARN),
+		RoleSessionName:  aws.String(input.RoleSessionName),
+		WebIdentityToken: aws.String(input.WebIdentityToken),
+	})
+	if err != nil {
+		return aws.Credentials{}, errors.Wrapf(err, "could not assume %s with web identity", input.RoleARN)
+	}
+	if output.Credentials == nil {
+		return aws.Credentials{}, errors.Errorf("no credentials returned assuming %s", input.RoleARN)
+	}
+
+	return aws.Credentials{
+		AccessKeyID:     aws.ToString(output.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(output.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(output.Credentials.SessionToken),
+		Source:          "AssumeRoleWithWebIdentity",
+		CanExpire:       true,
+		Expires:         aws.ToTime(output.Credentials.Expiration),
+	}, nil
+}
+
+// InvokeWithQualifier synchronously invokes a lambda function and returns its payload
+func (s *SDK) InvokeWithQualifier(ctx context.Context, input *InvokeInput) ([]byte, error) {
+	err := checkPartition(s.partition, input.Region)
+	if err != nil {
+		return nil, err
+	}
+	output, err := s.lambda.Invoke(
+		ctx,
+		&lambda.InvokeInput{
+			FunctionName:   aws.String(input.FunctionName),
+			Qualifier:      input.Qualifier,
+			Payload:        input.Payload,
+			InvocationType: lambdatypes.InvocationTypeRequestResponse,
+		},
+		func(o *lambda.Options) {
+			o.Region = input.Region
+			if input.Credentials != nil {
+				o.Credentials = input.Credentials
+			}
+			if endpoint := s.lambdaEndpoints[input.Region]; endpoint != "" {
+				o.BaseEndpoint = aws.String(endpoint)
+			}
+		},
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Error invoking lambda function %s", input.FunctionName)
+	}
+	return output.Payload, nil
+}
+
+// PresignGetCallerIdentity presigns an sts:GetCallerIdentity request with the default credentials
+func (s *SDK) PresignGetCallerIdentity(ctx context.Context) (*v4.PresignedHTTPRequest, error) {
+	req, err := sts.NewPresignClient(s.sts).PresignGetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	return req, errors.Wrap(err, "could not presign sts:GetCallerIdentity")
+}
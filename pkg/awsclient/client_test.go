@@ -0,0 +1,241 @@
+package awsclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// testEnv isolates the sdk from the environment it runs in
+func testEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", path.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path.Join(dir, "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "us-west-2")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+}
+
+func TestAssumeRoleWithWebIdentity(t *testing.T) {
+	r := require.New(t)
+	testEnv(t)
+
+	expiration := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.NoError(req.ParseForm())
+		r.Equal("AssumeRoleWithWebIdentity", req.Form.Get("Action"))
+		r.Equal("oidc-token", req.Form.Get("WebIdentityToken"))
+		r.Equal("arn:aws:iam::123456789012:role/bless", req.Form.Get("RoleArn"))
+		fmt.Fprintf(w, `<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>
+<AccessKeyId>ASIA</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>session</SessionToken>
+<Expiration>%s</Expiration></Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`,
+			expiration.Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	client, err := New(context.Background(), &config.AWSConfig{STSEndpoint: server.URL})
+	r.NoError(err)
+
+	creds, err := NewWebIdentityProvider(client, "arn:aws:iam::123456789012:role/bless", "user", "oidc-token").
+		Retrieve(context.Background())
+	r.NoError(err)
+	r.Equal("ASIA", creds.AccessKeyID)
+	r.Equal("session", creds.SessionToken)
+	r.True(creds.CanExpire)
+	r.Equal(expiration, creds.Expires)
+}
+
+func TestInvokeWithQualifier(t *testing.T) {
+	r := require.New(t)
+	testEnv(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.Equal("/2015-03-31/functions/bless/invocations", req.URL.Path)
+		r.Equal("live", req.URL.Query().Get("Qualifier"))
+		// signed for the region we invoked in, with the credentials we passed
+		r.Contains(req.Header.Get("Authorization"), "ASIA/")
+		r.Contains(req.Header.Get("Authorization"), "/eu-west-1/lambda/")
+		body, err := ioutil.ReadAll(req.Body)
+		r.NoError(err)
+		r.Equal(`{"hello":"world"}`, string(body))
+		fmt.Fprint(w, `{"certificate":{}}`)
+	}))
+	defer server.Close()
+
+	client, err := New(context.Background(), &config.AWSConfig{
+		LambdaEndpoints: map[string]string{"eu-west-1": server.URL},
+	})
+	r.NoError(err)
+
+	payload, err := client.InvokeWithQualifier(context.Background(), &InvokeInput{
+		Region:       "eu-west-1",
+		FunctionName: "bless",
+		Qualifier:    aws.String("live"),
+		Payload:      []byte(`{"hello":"world"}`),
+		Credentials:  aws.NewCredentialsCache(staticProvider("ASIA")),
+	})
+	r.NoError(err)
+	r.Equal(`{"certificate":{}}`, string(payload))
+}
+
+type staticProvider string
+
+func (p staticProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	return aws.Credentials{AccessKeyID: string(p), SecretAccessKey: "secret"}, nil
+}
+
+func TestPresignGetCallerIdentity(t *testing.T) {
+	r := require.New(t)
+	testEnv(t)
+
+	client, err := New(context.Background(), nil)
+	r.NoError(err)
+
+	req, err := client.PresignGetCallerIdentity(context.Background())
+	r.NoError(err)
+	r.Contains(req.URL, "https://sts.us-west-2.amazonaws.com")
+	r.Contains(req.URL, "Action=GetCallerIdentity")
+	r.Contains(req.URL, "X-Amz-Signature=")
+	r.Contains(req.URL, "AKIDEXAMPLE")
+	// make sure we don't leak the secret key
+	r.NotContains(req.URL, "secret")
+}
+
+func TestNewUnknownPartition(t *testing.T) {
+	r := require.New(t)
+	testEnv(t)
+
+	_, err := New(context.Background(), &config.AWSConfig{Partition: "aws-mars"})
+	r.Error(err)
+}
+
+func TestNewRegionOutsidePartition(t *testing.T) {
+	r := require.New(t)
+	testEnv(t)
+
+	// AWS_REGION is us-west-2
+	_, err := New(context.Background(), &config.AWSConfig{Partition: "aws-us-gov"})
+	r.Error(err)
+	r.True(errors.Is(err, ErrRegionNotInPartition))
+
+	t.Setenv("AWS_REGION", "us-gov-west-1")
+	client, err := New(context.Background(), &config.AWSConfig{Partition: "aws-us-gov"})
+	r.NoError(err)
+	_, err = client.InvokeWithQualifier(context.Background(), &InvokeInput{Region: "us-west-2", FunctionName: "bless"})
+	r.Error(err)
+	r.True(errors.Is(err, ErrRegionNotInPartition))
+	r.Contains(err.Error(), "region us-west-2 is not in partition aws-us-gov")
+}
+
+func TestRegionPartition(t *testing.T) {
+	r := require.New(t)
+	r.Equal("aws", regionPartition("us-west-2"))
+	r.Equal("aws", regionPartition("eu-west-1"))
+	r.Equal("aws-us-gov", regionPartition("us-gov-east-1"))
+	r.Equal("aws-cn", regionPartition("cn-northwest-1"))
+	r.Equal("aws-iso", regionPartition("us-iso-east-1"))
+	r.Equal("aws-iso-b", regionPartition("us-isob-east-1"))
+}
+
+func TestInvokeRetries(t *testing.T) {
+	cases := map[string]struct {
+		status    int
+		errorType string
+		attempts  int
+	}{
+		// the sdk backs off on throttling
+		"throttled": {http.StatusTooManyRequests, "TooManyRequestsException", 2},
+		// failover takes care of everything else
+		"service error": {http.StatusInternalServerError, "ServiceException", 1},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			testEnv(t)
+
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				attempts++
+				if attempts == 1 {
+					w.Header().Set("X-Amzn-ErrorType", c.errorType)
+					w.WriteHeader(c.status)
+					fmt.Fprint(w, `{"message":"nope"}`)
+					return
+				}
+				fmt.Fprint(w, `{"certificate":{}}`)
+			}))
+			defer server.Close()
+
+			client, err := New(context.Background(), &config.AWSConfig{
+				LambdaEndpoints: map[string]string{"us-west-2": server.URL},
+			})
+			r.NoError(err)
+			_, _ = client.InvokeWithQualifier(context.Background(), &InvokeInput{
+				Region:       "us-west-2",
+				FunctionName: "bless",
+			})
+			r.Equal(c.attempts, attempts)
+		})
+	}
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	r := require.New(t)
+	httpClient, err := newHTTPClient(&config.AWSConfig{ProxyURL: "http://proxy.example.com:3128"})
+	r.NoError(err)
+
+	req, err := http.NewRequest(http.MethodGet, "https://sts.amazonaws.com", nil)
+	r.NoError(err)
+	proxy, err := httpClient.GetTransport().Proxy(req)
+	r.NoError(err)
+	r.Equal("http://proxy.example.com:3128", proxy.String())
+}
+
+func TestNewHTTPClientCABundle(t *testing.T) {
+	r := require.New(t)
+	dir, err := ioutil.TempDir("", "blessclient-awsclient")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	r.NoError(err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	r.NoError(err)
+	caBundle := path.Join(dir, "ca.pem")
+	r.NoError(ioutil.WriteFile(caBundle, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+
+	httpClient, err := newHTTPClient(&config.AWSConfig{CABundle: caBundle})
+	r.NoError(err)
+	r.NotNil(httpClient.GetTransport().TLSClientConfig.RootCAs)
+
+	_, err = newHTTPClient(&config.AWSConfig{CABundle: path.Join(dir, "missing.pem")})
+	r.Error(err)
+}
@@ -0,0 +1,31 @@
+package awsclient
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// WebIdentityProvider provides credentials by assuming a role with an oidc token
+type WebIdentityProvider struct {
+	client Client
+	input  AssumeRoleWithWebIdentityInput
+}
+
+// NewWebIdentityProvider returns a provider that assumes roleARN with webIdentityToken
+func NewWebIdentityProvider(client Client, roleARN string, sessionName string, webIdentityToken string) *WebIdentityProvider {
+	return &WebIdentityProvider{
+		client: client,
+		input: AssumeRoleWithWebIdentityInput{
+			RoleARN:          roleARN,
+			RoleSessionName:  sessionName,
+			WebIdentityToken: webIdentityToken,
+		},
+	}
+}
+
+// Retrieve assumes the role
+func (p *WebIdentityProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	input := p.input
+	return p.client.AssumeRoleWithWebIdentity(ctx, &input)
+}
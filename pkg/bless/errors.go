@@ -0,0 +1,164 @@
+package bless
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/smithy-go"
+	"github.com/chanzuckerberg/blessclient/pkg/awsclient"
+	"github.com/pkg/errors"
+)
+
+// Kinds of errors we can get back when requesting a certificate.
+// Use errors.Is to check which kind an error returned by RequestCert is.
+var (
+	// ErrAccessDenied means the CA (or AWS) refused to sign for this identity
+	ErrAccessDenied = errors.New("access denied")
+	// ErrInvalidKey means the CA refused to sign the public key we sent
+	ErrInvalidKey = errors.New("invalid public key")
+	// ErrThrottled means the lambda or the CA is rate limiting us
+	ErrThrottled = errors.New("throttled")
+	// ErrCAUnavailable means the CA could not be reached or failed to run
+	ErrCAUnavailable = errors.New("CA unavailable")
+	// ErrCAMisconfigured means lambda_config points at a CA that can't run, retrying won't help
+	ErrCAMisconfigured = errors.New("CA misconfigured")
+	// ErrRejected means the CA returned an error we don't know about
+	ErrRejected = errors.New("request rejected")
+	// ErrAuthFailed means we could not log in or assume the role to call the CA with,
+	// client.AuthError matches it
+	ErrAuthFailed = errors.New("authentication failed")
+)
+
+// error types returned by the bless lambda in Response.ErrorType
+var caErrorTypes = map[string]error{
+	"AccessDenied":       ErrAccessDenied,
+	"Unauthorized":       ErrAccessDenied,
+	"Forbidden":          ErrAccessDenied,
+	"InvalidIdentity":    ErrAccessDenied,
+	"InvalidToken":       ErrAccessDenied,
+	"ExpiredToken":       ErrAccessDenied,
+	"InvalidKey":         ErrInvalidKey,
+	"InvalidPublicKey":   ErrInvalidKey,
+	"UnsafePublicKey":    ErrInvalidKey,
+	"UnsupportedKeyType": ErrInvalidKey,
+	"Throttled":          ErrThrottled,
+	"TooManyRequests":    ErrThrottled,
+}
+
+// error codes returned by the lambda Invoke api, and by sts when
+// the credentials we invoke with are resolved lazily
+var invokeErrorCodes = map[string]error{
+	"AccessDeniedException":       ErrAccessDenied,
+	"UnrecognizedClientException": ErrAccessDenied,
+	"ExpiredTokenException":       ErrAccessDenied,
+	"AccessDenied":                ErrAccessDenied,
+	"ExpiredToken":                ErrAccessDenied,
+	"InvalidIdentityToken":        ErrAccessDenied,
+	"IDPRejectedClaim":            ErrAccessDenied,
+	"TooManyRequestsException":    ErrThrottled,
+	"EC2ThrottledException":       ErrThrottled,
+	"ThrottlingException":         ErrThrottled,
+	"ServiceException":            ErrCAUnavailable,
+	"ResourceNotReadyException":   ErrCAUnavailable,
+	"ResourceNotFoundException":   ErrCAMisconfigured,
+	"ResourceConflictException":   ErrCAMisconfigured,
+	"KMSAccessDeniedException":    ErrCAMisconfigured,
+	"KMSDisabledException":        ErrCAMisconfigured,
+}
+
+// Error is an error returned while requesting a certificate
+type Error struct {
+	// Kind is one of the Err* values in this package
+	Kind error
+	// Type is the error type reported by the lambda or aws
+	Type string
+	// Message is the error message reported by the lambda or aws
+	Message string
+}
+
+// Error returns the string representation of this error
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("bless error (%s): %s", e.Kind, e.Type)
+	}
+	return fmt.Sprintf("bless error (%s): %s: %s", e.Kind, e.Type, e.Message)
+}
+
+// Unwrap returns the kind of this error so errors.Is works
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// newResponseError maps an error returned in the lambda response
+func newResponseError(errorType string, errorMessage *string) *Error {
+	e := &Error{
+		Kind: ErrRejected,
+		Type: errorType,
+	}
+	if errorMessage != nil {
+		e.Message = *errorMessage
+	}
+
+	kind, ok := caErrorTypes[errorType]
+	switch {
+	case ok:
+		e.Kind = kind
+	// errors raised by the lambda runtime itself (crashes, timeouts, out of memory)
+	case strings.HasPrefix(errorType, "Runtime."),
+		strings.HasPrefix(errorType, "Sandbox."),
+		strings.HasPrefix(errorType, "Function."):
+		e.Kind = ErrCAUnavailable
+	}
+	return e
+}
+
+// newInvokeError maps an error returned when invoking the lambda.
+// Anything that isn't an aws api error, like a timeout or a network error, means the CA is unavailable.
+// Api errors we don't know about are rejections, we don't retry them.
+func newInvokeError(err error) error {
+	if errors.Is(err, awsclient.ErrRegionNotInPartition) {
+		return &Error{Kind: ErrCAMisconfigured, Type: "InvokeError", Message: err.Error()}
+	}
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return &Error{Kind: ErrCAUnavailable, Type: "InvokeError", Message: err.Error()}
+	}
+
+	kind, ok := invokeErrorCodes[apiErr.ErrorCode()]
+	if !ok {
+		kind = ErrRejected
+	}
+	return &Error{Kind: kind, Type: apiErr.ErrorCode(), Message: apiErr.ErrorMessage()}
+}
+
+// IsRetryable returns true if err might succeed in another region or on a later attempt
+func IsRetryable(err error) bool {
+	return !errors.Is(err, ErrAccessDenied) && !errors.Is(err, ErrInvalidKey)
+}
+
+// Remediation returns a human readable hint on how to fix err, or "" if we have none
+func Remediation(err error) string {
+	switch {
+	case errors.Is(err, ErrAuthFailed):
+		return "blessclient could not log in or assume client_config.role_arn. " +
+			"Check role_arn in ~/.blessclient/config.yml and that its trust policy allows your oidc client, " +
+			"then try `blessclient run --force` to log in again."
+	case errors.Is(err, ErrAccessDenied):
+		return "The CA refused to sign a certificate for you. " +
+			"Make sure you are in the right groups for this CA and try `blessclient run --force` to log in again."
+	case errors.Is(err, ErrInvalidKey):
+		return "The CA refused to sign your public key. " +
+			"Generate a new key with `ssh-keygen -t ed25519` and try again."
+	case errors.Is(err, ErrThrottled):
+		return "The CA is rate limiting requests. Wait a few seconds and try again."
+	case errors.Is(err, ErrCAMisconfigured):
+		return "The CA lambda can't run as configured. " +
+			"Check lambda_config.function_name, function_version and regions in ~/.blessclient/config.yml, " +
+			"or ask the CA's owners to check its deployment and kms key."
+	case errors.Is(err, ErrCAUnavailable):
+		return "The CA could not be reached. " +
+			"Check your network connection and the lambda_config in ~/.blessclient/config.yml, or try again later."
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,96 @@
+package bless
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/smithy-go"
+	"github.com/chanzuckerberg/blessclient/pkg/awsclient"
+	"github.com/chanzuckerberg/blessclient/pkg/awsclient/mocks"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseErrors(t *testing.T) {
+	r := require.New(t)
+
+	cases := []struct {
+		payload  string
+		kind     error
+		contains string
+	}{
+		{`{"errorType": "AccessDenied", "errorMessage": "not in group"}`, ErrAccessDenied, "not in group"},
+		{`{"errorType": "UnsafePublicKey"}`, ErrInvalidKey, "UnsafePublicKey"},
+		{`{"errorType": "Throttled"}`, ErrThrottled, "Throttled"},
+		{`{"errorType": "Runtime.ExitError", "errorMessage": "exit status 2"}`, ErrCAUnavailable, "exit status 2"},
+		{`{"errorType": "SomethingNew"}`, ErrRejected, "SomethingNew"},
+	}
+
+	for _, c := range cases {
+		ctrl := gomock.NewController(t)
+		awsClient := mocks.NewMockClient(ctrl)
+		awsClient.EXPECT().InvokeWithQualifier(gomock.Any(), gomock.Any()).Return([]byte(c.payload), nil)
+
+		client := NewOIDC(awsClient, &config.LambdaConfig{FunctionName: "bless"})
+		_, err := client.getCert(context.Background(), config.Region{AWSRegion: "us-west-2"}, nil, []byte("{}"))
+		r.Error(err)
+		r.True(errors.Is(err, c.kind), "expected %s to be %s", err, c.kind)
+		r.Contains(err.Error(), c.contains)
+		ctrl.Finish()
+	}
+}
+
+func TestInvokeErrors(t *testing.T) {
+	r := require.New(t)
+
+	cases := []struct {
+		err  error
+		kind error
+	}{
+		{&smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not allowed to invoke"}, ErrAccessDenied},
+		// sts errors from resolving the credentials we invoke with
+		{&smithy.GenericAPIError{Code: "AccessDenied", Message: "not authorized to perform sts:AssumeRoleWithWebIdentity"}, ErrAccessDenied},
+		{&smithy.GenericAPIError{Code: "InvalidIdentityToken", Message: "token is expired"}, ErrAccessDenied},
+		{&smithy.GenericAPIError{Code: "IDPRejectedClaim", Message: "bad audience"}, ErrAccessDenied},
+		{&lambdatypes.TooManyRequestsException{Message: aws.String("slow down")}, ErrThrottled},
+		{errors.Wrap(&lambdatypes.ServiceException{Message: aws.String("oops")}, "wrapped"), ErrCAUnavailable},
+		{errors.New("connection reset"), ErrCAUnavailable},
+		{&lambdatypes.ResourceNotFoundException{Message: aws.String("Function not found")}, ErrCAMisconfigured},
+		{&lambdatypes.KMSDisabledException{Message: aws.String("key disabled")}, ErrCAMisconfigured},
+		{&smithy.GenericAPIError{Code: "SomethingNew"}, ErrRejected},
+		{errors.Wrap(awsclient.ErrRegionNotInPartition, "region us-west-2 is not in partition aws-us-gov"), ErrCAMisconfigured},
+	}
+
+	for _, c := range cases {
+		ctrl := gomock.NewController(t)
+		awsClient := mocks.NewMockClient(ctrl)
+		awsClient.EXPECT().InvokeWithQualifier(gomock.Any(), gomock.Any()).Return(nil, c.err)
+
+		client := NewOIDC(awsClient, &config.LambdaConfig{
+			FunctionName:    "bless",
+			FunctionVersion: aws.String("live"),
+		})
+		_, err := client.getCert(context.Background(), config.Region{AWSRegion: "us-west-2"}, nil, []byte("{}"))
+		r.Error(err)
+		r.True(errors.Is(err, c.kind), "expected %s to be %s", err, c.kind)
+		ctrl.Finish()
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	r := require.New(t)
+
+	r.False(IsRetryable(&Error{Kind: ErrAccessDenied}))
+	r.False(IsRetryable(errors.Wrap(&Error{Kind: ErrInvalidKey}, "wrapped")))
+	r.True(IsRetryable(&Error{Kind: ErrThrottled}))
+	r.True(IsRetryable(&Error{Kind: ErrCAUnavailable}))
+	r.True(IsRetryable(errors.New("something else")))
+
+	r.NotEmpty(Remediation(&Error{Kind: ErrAccessDenied}))
+	r.Contains(Remediation(&Error{Kind: ErrCAMisconfigured}), "lambda_config.function_name")
+	r.Empty(Remediation(errors.New("something else")))
+}
@@ -0,0 +1,201 @@
+package bless
+
+import (
+	"context"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// RegionalRequest requests a certificate from a single region
+type RegionalRequest func(ctx context.Context, region config.Region) (*ssh.Certificate, error)
+
+// Failover requests certificates across multiple regions
+type Failover struct {
+	regions  []config.Region
+	failover config.FailoverConfig
+	health   *RegionHealth
+	logger   logrus.FieldLogger
+
+	newBackOff func() backoff.BackOff
+}
+
+func defaultBackOff() backoff.BackOff {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = 250 * time.Millisecond
+	b.MaxInterval = 2 * time.Second
+	// jitter so concurrent clients don't retry in lockstep
+	b.RandomizationFactor = 0.5
+	// bounded by retries and timeouts instead
+	b.MaxElapsedTime = 0
+	return b
+}
+
+// NewFailover returns a new Failover. If health is nil we don't remember anything.
+func NewFailover(lambdaConfig *config.LambdaConfig, health *RegionHealth) *Failover {
+	if health == nil {
+		health = NewRegionHealth("")
+	}
+	return &Failover{
+		regions:  lambdaConfig.Regions,
+		failover: lambdaConfig.GetFailover(),
+		health:   health,
+		logger:   logrus.StandardLogger(),
+
+		newBackOff: defaultBackOff,
+	}
+}
+
+// SetLogger sets where f logs, the standard logger by default
+func (f *Failover) SetLogger(logger logrus.FieldLogger) {
+	f.logger = logger
+}
+
+type regionalResult struct {
+	cert *ssh.Certificate
+	err  error
+}
+
+// Do runs request against each region until one succeeds or
+// we hit an error that will fail in every region.
+func (f *Failover) Do(ctx context.Context, request RegionalRequest) (*ssh.Certificate, error) {
+	var errs *multierror.Error
+
+	ctx, cancel := context.WithTimeout(ctx, f.failover.Timeout)
+	defer cancel()
+
+	regions := f.health.Order(f.regions, f.failover)
+	if len(regions) == 0 {
+		return nil, errors.New("no lambda regions configured")
+	}
+
+	if f.failover.HedgeDelay > 0 && len(regions) >= 2 {
+		cert, err := f.hedge(ctx, regions[0], regions[1], request)
+		if err == nil {
+			return cert, nil
+		}
+		errs = multierror.Append(errs, err)
+		if !IsRetryable(err) {
+			return nil, errs.ErrorOrNil()
+		}
+		regions = regions[2:]
+	}
+
+	for _, region := range regions {
+		res := f.do(ctx, region, request)
+		if res.err == nil {
+			return res.cert, nil
+		}
+		errs = multierror.Append(errs, res.err)
+
+		// no point in trying other regions if they will refuse us too
+		if !IsRetryable(res.err) {
+			f.logger.WithError(res.err).Debugf("non-retryable error from region %s", region.AWSRegion)
+			break
+		}
+	}
+	return nil, errs.ErrorOrNil()
+}
+
+// hedge races first and second, giving first a head start of HedgeDelay.
+// The first success wins and the other request is canceled.
+func (f *Failover) hedge(
+	ctx context.Context,
+	first config.Region,
+	second config.Region,
+	request RegionalRequest,
+) (*ssh.Certificate, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan regionalResult, 2)
+	launch := func(region config.Region) {
+		go func() { results <- f.do(ctx, region, request) }()
+	}
+	launch(first)
+
+	var errs *multierror.Error
+	pending := 1
+	secondStarted := false
+	timer := time.NewTimer(f.failover.HedgeDelay)
+	defer timer.Stop()
+
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if !secondStarted {
+				f.logger.Debugf("region %s is slow, also trying %s", first.AWSRegion, second.AWSRegion)
+				secondStarted = true
+				pending++
+				launch(second)
+			}
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				return res.cert, nil
+			}
+			errs = multierror.Append(errs, res.err)
+			if !IsRetryable(res.err) {
+				return nil, errs.ErrorOrNil()
+			}
+			if !secondStarted {
+				secondStarted = true
+				pending++
+				launch(second)
+			}
+		}
+	}
+	return nil, errs.ErrorOrNil()
+}
+
+// do runs request against a single region, retrying transient errors,
+// and records how it went
+func (f *Failover) do(ctx context.Context, region config.Region, request RegionalRequest) regionalResult {
+	f.logger.Debugf("Attempting to get cert from region %s", region.AWSRegion)
+
+	var cert *ssh.Certificate
+	var latency time.Duration
+
+	attempt := func() error {
+		attemptCtx, cancel := context.WithTimeout(ctx, f.failover.RegionTimeout)
+		defer cancel()
+
+		start := time.Now()
+		var err error
+		cert, err = request(attemptCtx, region)
+		latency = time.Since(start)
+		if err != nil && !isTransient(err) {
+			return backoff.Permanent(err)
+		}
+		return err
+	}
+	notify := func(err error, next time.Duration) {
+		f.logger.WithError(err).Debugf("transient error from region %s, retrying in %s", region.AWSRegion, next)
+	}
+
+	// WithMaxRetries treats 0 as unlimited
+	var b backoff.BackOff = &backoff.StopBackOff{}
+	if f.failover.Retries > 0 {
+		b = backoff.WithMaxRetries(f.newBackOff(), uint64(f.failover.Retries))
+	}
+	err := backoff.RetryNotify(attempt, backoff.WithContext(b, ctx), notify)
+	switch {
+	case err == nil:
+		f.health.RecordSuccess(region.AWSRegion, latency)
+	case ctx.Err() != nil:
+		// we were canceled, that says nothing about the region
+	case isTransient(err):
+		f.health.RecordFailure(region.AWSRegion)
+	}
+	return regionalResult{cert: cert, err: err}
+}
+
+// isTransient returns true if retrying the same region might help
+func isTransient(err error) bool {
+	return errors.Is(err, ErrCAUnavailable) || errors.Is(err, ErrThrottled)
+}
@@ -0,0 +1,231 @@
+package bless
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestFailover(lambdaConfig *config.LambdaConfig, health *RegionHealth) *Failover {
+	f := NewFailover(lambdaConfig, health)
+	f.newBackOff = func() backoff.BackOff { return &backoff.ZeroBackOff{} }
+	return f
+}
+
+func testLambdaConfig(failover *config.FailoverConfig, regions ...string) *config.LambdaConfig {
+	lambdaConfig := &config.LambdaConfig{Failover: failover}
+	for _, region := range regions {
+		lambdaConfig.Regions = append(lambdaConfig.Regions, config.Region{AWSRegion: region})
+	}
+	return lambdaConfig
+}
+
+// recorder records which regions were called and returns canned results
+type recorder struct {
+	mu      sync.Mutex
+	called  []string
+	results map[string]error
+	delays  map[string]time.Duration
+}
+
+func (r *recorder) request(ctx context.Context, region config.Region) (*ssh.Certificate, error) {
+	r.mu.Lock()
+	r.called = append(r.called, region.AWSRegion)
+	err := r.results[region.AWSRegion]
+	delay := r.delays[region.AWSRegion]
+	r.mu.Unlock()
+
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ssh.Certificate{KeyId: region.AWSRegion}, nil
+}
+
+func TestFailoverStopsOnNonRetryable(t *testing.T) {
+	r := require.New(t)
+	rec := &recorder{results: map[string]error{
+		"us-west-2": &Error{Kind: ErrAccessDenied},
+	}}
+
+	f := newTestFailover(testLambdaConfig(nil, "us-west-2", "us-east-1"), nil)
+	_, err := f.Do(context.Background(), rec.request)
+	r.Error(err)
+	r.True(errors.Is(err, ErrAccessDenied))
+	r.Equal([]string{"us-west-2"}, rec.called)
+}
+
+func TestFailoverSkipsCoolingRegions(t *testing.T) {
+	r := require.New(t)
+	rec := &recorder{results: map[string]error{
+		"us-west-2": &Error{Kind: ErrCAUnavailable},
+	}}
+	health := NewRegionHealth("")
+	lambdaConfig := testLambdaConfig(&config.FailoverConfig{Retries: -1}, "us-west-2", "us-east-1")
+
+	cert, err := newTestFailover(lambdaConfig, health).Do(context.Background(), rec.request)
+	r.NoError(err)
+	r.Equal("us-east-1", cert.KeyId)
+	r.Equal([]string{"us-west-2", "us-east-1"}, rec.called)
+
+	// us-west-2 is cooling down so we skip it
+	rec.called = nil
+	cert, err = newTestFailover(lambdaConfig, health).Do(context.Background(), rec.request)
+	r.NoError(err)
+	r.Equal("us-east-1", cert.KeyId)
+	r.Equal([]string{"us-east-1"}, rec.called)
+
+	// once the cooldown is over we go back to the configured order
+	health.now = func() time.Time { return time.Now().Add(config.DefaultRegionCooldown) }
+	r.Equal(lambdaConfig.Regions, health.Order(lambdaConfig.Regions, lambdaConfig.GetFailover()))
+}
+
+func TestFailoverAllRegionsCooling(t *testing.T) {
+	r := require.New(t)
+	rec := &recorder{results: map[string]error{
+		"us-west-2": &Error{Kind: ErrCAUnavailable},
+		"us-east-1": &Error{Kind: ErrCAUnavailable},
+	}}
+	health := NewRegionHealth("")
+	lambdaConfig := testLambdaConfig(&config.FailoverConfig{Retries: -1}, "us-west-2", "us-east-1")
+
+	_, err := newTestFailover(lambdaConfig, health).Do(context.Background(), rec.request)
+	r.Error(err)
+
+	// every region is cooling down, so we try them all rather than none
+	rec.called = nil
+	rec.results = map[string]error{}
+	cert, err := newTestFailover(lambdaConfig, health).Do(context.Background(), rec.request)
+	r.NoError(err)
+	r.Equal("us-west-2", cert.KeyId)
+	r.Equal([]string{"us-west-2"}, rec.called)
+}
+
+func TestFailoverRetriesTransientErrors(t *testing.T) {
+	r := require.New(t)
+	rec := &recorder{results: map[string]error{
+		"us-west-2": &Error{Kind: ErrThrottled},
+		"us-east-1": &Error{Kind: ErrRejected},
+	}}
+	lambdaConfig := testLambdaConfig(&config.FailoverConfig{Retries: 2}, "us-west-2", "us-east-1")
+
+	_, err := newTestFailover(lambdaConfig, nil).Do(context.Background(), rec.request)
+	r.Error(err)
+	// throttles are retried in the same region, unknown rejections are not
+	r.Equal([]string{"us-west-2", "us-west-2", "us-west-2", "us-east-1"}, rec.called)
+}
+
+func TestFailoverMisconfiguredRegion(t *testing.T) {
+	r := require.New(t)
+	rec := &recorder{results: map[string]error{
+		"us-west-2": &Error{Kind: ErrCAMisconfigured, Type: "ResourceNotFoundException"},
+	}}
+	health := NewRegionHealth("")
+	lambdaConfig := testLambdaConfig(&config.FailoverConfig{Retries: 2}, "us-west-2", "us-east-1")
+
+	cert, err := newTestFailover(lambdaConfig, health).Do(context.Background(), rec.request)
+	r.NoError(err)
+	r.Equal("us-east-1", cert.KeyId)
+	// not retried and the region isn't marked unhealthy, it is broken for everyone
+	r.Equal([]string{"us-west-2", "us-east-1"}, rec.called)
+	r.True(health.Regions["us-west-2"] == nil || health.Regions["us-west-2"].LastFailure.IsZero())
+}
+
+func TestFailoverRegionTimeout(t *testing.T) {
+	r := require.New(t)
+	rec := &recorder{delays: map[string]time.Duration{
+		"us-west-2": time.Minute,
+	}}
+	lambdaConfig := testLambdaConfig(
+		&config.FailoverConfig{RegionTimeout: 10 * time.Millisecond, Retries: -1},
+		"us-west-2", "us-east-1")
+	health := NewRegionHealth("")
+
+	// the recorder returns the raw context error, like a stuck invocation would
+	rec.results = map[string]error{}
+	cert, err := newTestFailover(lambdaConfig, health).Do(context.Background(), func(ctx context.Context, region config.Region) (*ssh.Certificate, error) {
+		cert, err := rec.request(ctx, region)
+		if err != nil {
+			return nil, newInvokeError(err)
+		}
+		return cert, nil
+	})
+	r.NoError(err)
+	r.Equal("us-east-1", cert.KeyId)
+	r.False(health.Regions["us-west-2"].LastFailure.IsZero())
+}
+
+func TestFailoverHedge(t *testing.T) {
+	r := require.New(t)
+	rec := &recorder{delays: map[string]time.Duration{
+		"us-west-2": time.Minute,
+	}}
+	lambdaConfig := testLambdaConfig(
+		&config.FailoverConfig{HedgeDelay: 10 * time.Millisecond},
+		"us-west-2", "us-east-1")
+	health := NewRegionHealth("")
+
+	cert, err := newTestFailover(lambdaConfig, health).Do(context.Background(), rec.request)
+	r.NoError(err)
+	r.Equal("us-east-1", cert.KeyId)
+	r.ElementsMatch([]string{"us-west-2", "us-east-1"}, rec.called)
+	// the slow region was canceled, not failed
+	r.True(health.Regions["us-west-2"] == nil || health.Regions["us-west-2"].LastFailure.IsZero())
+}
+
+func TestRegionHealthOrderByLatency(t *testing.T) {
+	r := require.New(t)
+	health := NewRegionHealth("")
+	health.RecordSuccess("us-west-2", 300*time.Millisecond)
+	health.RecordSuccess("us-east-1", 100*time.Millisecond)
+
+	lambdaConfig := testLambdaConfig(
+		&config.FailoverConfig{OrderByLatency: true},
+		"us-west-2", "us-east-1", "eu-west-1")
+	ordered := health.Order(lambdaConfig.Regions, lambdaConfig.GetFailover())
+	// unmeasured regions come first so they get measured
+	r.Equal("eu-west-1", ordered[0].AWSRegion)
+	r.Equal("us-east-1", ordered[1].AWSRegion)
+	r.Equal("us-west-2", ordered[2].AWSRegion)
+}
+
+func TestRegionHealthPersist(t *testing.T) {
+	r := require.New(t)
+	dir, err := ioutil.TempDir("", "blessclient-region-health")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+	healthPath := path.Join(dir, "region_health.json")
+
+	health, err := LoadRegionHealth(healthPath)
+	r.NoError(err)
+	r.Empty(health.Regions)
+
+	health.RecordFailure("us-west-2")
+	health.RecordSuccess("us-east-1", time.Second)
+	r.NoError(health.Persist())
+
+	loaded, err := LoadRegionHealth(healthPath)
+	r.NoError(err)
+	r.False(loaded.Regions["us-west-2"].LastFailure.IsZero())
+	r.Equal(time.Second, loaded.Regions["us-east-1"].Latency)
+
+	// corrupt files are ignored
+	r.NoError(ioutil.WriteFile(healthPath, []byte("not json"), 0600))
+	loaded, err = LoadRegionHealth(healthPath)
+	r.NoError(err)
+	r.Empty(loaded.Regions)
+}
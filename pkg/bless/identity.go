@@ -0,0 +1,67 @@
+package bless
+
+import (
+	"context"
+	"net/http"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// Identity represents different types of identity assertions
+// that we can use. Only one should be set.
+type Identity struct {
+	OktaAccessToken          *OktaAccessTokenInput          `json:"okta_identity,omitempty"`
+	OIDCIDToken              *OIDCIDTokenInput              `json:"oidc_identity,omitempty"`
+	GithubActionsToken       *GithubActionsTokenInput       `json:"github_actions_identity,omitempty"`
+	AWSCallerIdentity        *AWSCallerIdentityInput        `json:"aws_identity,omitempty"`
+	KubernetesServiceAccount *KubernetesServiceAccountInput `json:"kubernetes_identity,omitempty"`
+}
+
+// OktaAccessTokenInput asserts identity with an okta access token
+type OktaAccessTokenInput struct {
+	AccessToken string
+}
+
+// OIDCIDTokenInput asserts identity with an oidc id token
+type OIDCIDTokenInput struct {
+	IDToken string
+}
+
+// GithubActionsTokenInput asserts identity with a GitHub Actions oidc token
+type GithubActionsTokenInput struct {
+	IDToken string
+}
+
+// KubernetesServiceAccountInput asserts identity with a
+// Kubernetes projected service account token
+type KubernetesServiceAccountInput struct {
+	Token string
+}
+
+// AWSCallerIdentityInput asserts identity with a presigned sts:GetCallerIdentity request.
+// The CA executes the request to find out who we are.
+type AWSCallerIdentityInput struct {
+	Method  string
+	URL     string
+	Headers http.Header
+}
+
+// CallerIdentityPresigner presigns sts:GetCallerIdentity requests
+type CallerIdentityPresigner interface {
+	PresignGetCallerIdentity(ctx context.Context) (*v4.PresignedHTTPRequest, error)
+}
+
+// NewAWSCallerIdentityInput presigns an sts:GetCallerIdentity request
+// with the credentials configured on presigner
+func NewAWSCallerIdentityInput(ctx context.Context, presigner CallerIdentityPresigner) (*AWSCallerIdentityInput, error) {
+	req, err := presigner.PresignGetCallerIdentity(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AWSCallerIdentityInput{
+		Method:  req.Method,
+		URL:     req.URL,
+		Headers: req.SignedHeader,
+	}, nil
+}
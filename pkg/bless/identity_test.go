@@ -0,0 +1,69 @@
+package bless
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdentityJSON(t *testing.T) {
+	r := require.New(t)
+
+	cases := []struct {
+		identity Identity
+		expected string
+	}{
+		{
+			Identity{OktaAccessToken: &OktaAccessTokenInput{AccessToken: "a"}},
+			`{"okta_identity":{"AccessToken":"a"}}`,
+		},
+		{
+			Identity{OIDCIDToken: &OIDCIDTokenInput{IDToken: "b"}},
+			`{"oidc_identity":{"IDToken":"b"}}`,
+		},
+		{
+			Identity{GithubActionsToken: &GithubActionsTokenInput{IDToken: "c"}},
+			`{"github_actions_identity":{"IDToken":"c"}}`,
+		},
+		{
+			Identity{KubernetesServiceAccount: &KubernetesServiceAccountInput{Token: "d"}},
+			`{"kubernetes_identity":{"Token":"d"}}`,
+		},
+	}
+
+	for _, c := range cases {
+		data, err := json.Marshal(c.identity)
+		r.NoError(err)
+		r.JSONEq(c.expected, string(data))
+	}
+}
+
+// fakePresigner presigns a fixed request
+type fakePresigner struct{}
+
+func (fakePresigner) PresignGetCallerIdentity(ctx context.Context) (*v4.PresignedHTTPRequest, error) {
+	return &v4.PresignedHTTPRequest{
+		Method:       http.MethodGet,
+		URL:          "https://sts.us-west-2.amazonaws.com/?Action=GetCallerIdentity&X-Amz-Signature=abc",
+		SignedHeader: http.Header{"Host": {"sts.us-west-2.amazonaws.com"}},
+	}, nil
+}
+
+func TestNewAWSCallerIdentityInput(t *testing.T) {
+	r := require.New(t)
+
+	input, err := NewAWSCallerIdentityInput(context.Background(), fakePresigner{})
+	r.NoError(err)
+	r.Equal(http.MethodGet, input.Method)
+	r.True(strings.HasPrefix(input.URL, "https://sts."))
+	r.Equal("sts.us-west-2.amazonaws.com", input.Headers.Get("Host"))
+
+	data, err := json.Marshal(Identity{AWSCallerIdentity: input})
+	r.NoError(err)
+	r.Contains(string(data), `"aws_identity":{"Method":"GET"`)
+}
@@ -8,9 +8,10 @@ import (
 	"encoding/json"
 	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/chanzuckerberg/blessclient/pkg/awsclient"
 	"github.com/chanzuckerberg/blessclient/pkg/config"
-	cziAWS "github.com/chanzuckerberg/go-misc/aws"
-	"github.com/davecgh/go-spew/spew"
+	"github.com/chanzuckerberg/blessclient/pkg/redact"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/ssh"
@@ -63,64 +64,77 @@ func (c *Certificate) UnmarshalJSON(data []byte) error {
 
 // OIDC is an oidc client
 type OIDC struct {
-	awsClient *cziAWS.Client
+	awsClient awsclient.Client
 
 	lambdaConfig *config.LambdaConfig
+	logger       logrus.FieldLogger
 }
 
 // NewOIDC returns a new OIDC client
 func NewOIDC(
-	awsClient *cziAWS.Client,
+	awsClient awsclient.Client,
 	lambdaConfig *config.LambdaConfig,
 ) *OIDC {
 	return &OIDC{
 		awsClient:    awsClient,
 		lambdaConfig: lambdaConfig,
+		logger:       logrus.StandardLogger(),
 	}
 }
 
-// RequestCert requests a new certificate
+// SetLogger sets where o logs, the standard logger by default
+func (o *OIDC) SetLogger(logger logrus.FieldLogger) {
+	o.logger = logger
+}
+
+// RequestCert requests a new certificate from the lambda in region,
+// invoking it with creds
 func (o *OIDC) RequestCert(
 	ctx context.Context,
-	awsClient *cziAWS.Client,
+	region config.Region,
+	creds aws.CredentialsProvider,
 	signingRequest *SigningRequest,
 ) (*ssh.Certificate, error) {
 	payload, err := json.Marshal(signingRequest)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not json marshal payload")
 	}
-	return o.getCert(ctx, payload)
+	return o.getCert(ctx, region, creds, payload)
 }
 
-func (o *OIDC) getCert(ctx context.Context, payload []byte) (*ssh.Certificate, error) {
-	responseBytes, err := o.awsClient.Lambda.ExecuteWithQualifier(
-		ctx,
-		o.lambdaConfig.FunctionName,
-		o.lambdaConfig.FunctionVersion,
-		payload,
-	)
+func (o *OIDC) getCert(
+	ctx context.Context,
+	region config.Region,
+	creds aws.CredentialsProvider,
+	payload []byte,
+) (*ssh.Certificate, error) {
+	responseBytes, err := o.awsClient.InvokeWithQualifier(ctx, &awsclient.InvokeInput{
+		Region:       region.AWSRegion,
+		FunctionName: o.lambdaConfig.FunctionName,
+		Qualifier:    o.lambdaConfig.FunctionVersion,
+		Payload:      payload,
+		Credentials:  creds,
+	})
 	if err != nil {
-		return nil, err
+		return nil, newInvokeError(err)
 	}
-	logrus.Debugf("Raw lambda response %s", string(responseBytes))
+	// the response carries the certificate, only log it redacted
+	o.logger.Debugf("Raw lambda response %s", redact.String(string(responseBytes)))
 	response := &Response{}
 	err = json.Unmarshal(responseBytes, response)
 	if err != nil {
 		return nil, errors.Wrap(err, "Could not deserialize lambda reponse")
 	}
 
-	logrus.Debugf("Parsed lambda response %s", spew.Sdump(response))
 	if response.ErrorType != nil {
-		if response.ErrorMessage != nil {
-			return nil, errors.Errorf("bless error: %s: %s", *response.ErrorType, *response.ErrorMessage)
-		}
-		return nil, errors.Errorf("bless error: %s", *response.ErrorType)
+		return nil, newResponseError(*response.ErrorType, response.ErrorMessage)
 	}
 
 	if response.Certificate == nil || response.Certificate.cert == nil {
 		return nil, errors.New("No certificate in response")
 	}
 
+	o.logger.Debugf("Parsed lambda response %s", redact.Certificate(response.Certificate.cert))
 	return response.Certificate.cert, nil
 }
 
@@ -134,16 +148,6 @@ type SigningRequest struct {
 	Identity Identity `json:"identity,omitempty"`
 }
 
-// Identity represents different types of identity assertions
-// that we can use
-type Identity struct {
-	OktaAccessToken *OktaAccessTokenInput `json:"okta_identity,omitempty"`
-}
-
-type OktaAccessTokenInput struct {
-	AccessToken string
-}
-
 type RemoteUsernames []string
 
 // String returns the string representation of RemoteUsernames
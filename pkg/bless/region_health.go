@@ -0,0 +1,170 @@
+package bless
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// RegionState is what we remember about a region
+type RegionState struct {
+	// LastFailure is the last time this region failed us
+	LastFailure time.Time `json:"last_failure,omitempty"`
+	// Latency is a moving average of successful request latencies
+	Latency time.Duration `json:"latency,omitempty"`
+}
+
+// RegionHealth remembers recently failing regions and their latencies
+// across blessclient invocations.
+type RegionHealth struct {
+	Regions map[string]*RegionState `json:"regions"`
+
+	path   string
+	now    func() time.Time
+	logger logrus.FieldLogger
+	mu     sync.Mutex
+}
+
+// NewRegionHealth returns an empty RegionHealth that persists to healthPath
+func NewRegionHealth(healthPath string) *RegionHealth {
+	return &RegionHealth{
+		Regions: map[string]*RegionState{},
+
+		path:   healthPath,
+		now:    time.Now,
+		logger: logrus.StandardLogger(),
+	}
+}
+
+// SetLogger sets where h logs, the standard logger by default
+func (h *RegionHealth) SetLogger(logger logrus.FieldLogger) {
+	h.logger = logger
+}
+
+// LoadRegionHealth reads region health from healthPath.
+// A missing or corrupt file is treated as no history.
+func LoadRegionHealth(healthPath string) (*RegionHealth, error) {
+	expandedPath, err := homedir.Expand(healthPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not expand %s", healthPath)
+	}
+	h := NewRegionHealth(expandedPath)
+
+	b, err := ioutil.ReadFile(expandedPath)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read region health at %s", healthPath)
+	}
+
+	err = json.Unmarshal(b, h)
+	if err != nil {
+		logrus.WithError(err).Debugf("could not parse region health at %s, ignoring it", healthPath)
+		return NewRegionHealth(expandedPath), nil
+	}
+	if h.Regions == nil {
+		h.Regions = map[string]*RegionState{}
+	}
+	return h, nil
+}
+
+// Persist writes region health back to disk. Concurrent blessclients share the file,
+// so we replace it atomically rather than risk a half written one.
+func (h *RegionHealth) Persist() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	err := os.MkdirAll(path.Dir(h.path), 0755) // #nosec
+	if err != nil {
+		return errors.Wrapf(err, "could not create %s", path.Dir(h.path))
+	}
+
+	b, err := json.Marshal(h)
+	if err != nil {
+		return errors.Wrap(err, "could not json marshal region health")
+	}
+	err = util.WriteFileAtomic(h.path, b, 0600)
+	return errors.Wrapf(err, "could not write region health to %s", h.path)
+}
+
+func (h *RegionHealth) state(region string) *RegionState {
+	s, ok := h.Regions[region]
+	if !ok {
+		s = &RegionState{}
+		h.Regions[region] = s
+	}
+	return s
+}
+
+// RecordSuccess records a successful request and its latency
+func (h *RegionHealth) RecordSuccess(region string, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.state(region)
+	s.LastFailure = time.Time{}
+	if s.Latency == 0 {
+		s.Latency = latency
+		return
+	}
+	s.Latency = (s.Latency + latency) / 2
+}
+
+// RecordFailure records that region failed us
+func (h *RegionHealth) RecordFailure(region string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.state(region).LastFailure = h.now()
+}
+
+// Order returns regions in the order we should try them.
+// Regions that failed within the cooldown are skipped,
+// unless they all did and we have nothing else to try.
+func (h *RegionHealth) Order(regions []config.Region, failover config.FailoverConfig) []config.Region {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	available := []config.Region{}
+	cooling := []config.Region{}
+	for _, region := range regions {
+		s, ok := h.Regions[region.AWSRegion]
+		if ok && failover.Cooldown > 0 && h.now().Sub(s.LastFailure) < failover.Cooldown {
+			h.logger.Debugf("region %s failed at %s, skipping it", region.AWSRegion, s.LastFailure)
+			cooling = append(cooling, region)
+			continue
+		}
+		available = append(available, region)
+	}
+
+	if failover.OrderByLatency {
+		// regions we haven't measured yet have a latency of 0 so they get tried and measured
+		sort.SliceStable(available, func(i, j int) bool {
+			return h.latency(available[i].AWSRegion) < h.latency(available[j].AWSRegion)
+		})
+	}
+	if len(available) == 0 {
+		h.logger.Debug("every region failed recently, trying them anyway")
+		return cooling
+	}
+	return available
+}
+
+func (h *RegionHealth) latency(region string) time.Duration {
+	s, ok := h.Regions[region]
+	if !ok {
+		return 0
+	}
+	return s.Latency
+}
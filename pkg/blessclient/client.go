@@ -0,0 +1,134 @@
+package blessclient
+
+import (
+	"context"
+	"crypto"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/chanzuckerberg/blessclient/pkg/awsclient"
+	cziClient "github.com/chanzuckerberg/blessclient/pkg/client"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/login"
+	oidc "github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sessionName = "blessclient"
+)
+
+// Client requests certificates from the CA described by a blessclient config
+type Client struct {
+	blessConfig *config.Config
+	tokens      cziClient.TokenProvider
+	signer      cziClient.Signer
+	logger      logrus.FieldLogger
+}
+
+// NewClient returns a Client for blessConfig. It returns a *config.ClientVersionError
+// if blessConfig requires a newer blessclient than the module version you depend on.
+func NewClient(blessConfig *config.Config, opts ...Option) (*Client, error) {
+	if blessConfig == nil {
+		return nil, errors.New("nil blessclient config")
+	}
+	err := blessConfig.CheckClientVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.logger == nil {
+		logger := logrus.New()
+		logger.SetOutput(ioutil.Discard)
+		o.logger = logger
+	}
+
+	var awsClient *awsclient.SDK
+	if o.transport != nil {
+		awsClient, err = awsclient.NewWithHTTPClient(
+			context.Background(),
+			blessConfig.AWS,
+			&http.Client{Transport: o.transport},
+		)
+	} else {
+		awsClient, err = awsclient.New(context.Background(), blessConfig.AWS)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens cziClient.TokenProvider
+	if o.tokenSource != nil {
+		tokens = tokenSourceProvider(awsClient, blessConfig.ClientConfig.RoleARN, o.tokenSource)
+	} else {
+		tokens = cziClient.InteractiveTokens(awsClient, blessConfig.ClientConfig.RoleARN, &login.Config{
+			ClientID:     blessConfig.ClientConfig.OIDCClientID,
+			IssuerURL:    blessConfig.ClientConfig.OIDCIssuerURL,
+			Method:       login.MethodAuto,
+			Scopes:       blessConfig.ClientConfig.OIDCScopes,
+			Audience:     blessConfig.ClientConfig.OIDCAudience,
+			RedirectPort: blessConfig.ClientConfig.OIDCRedirectPort,
+		})
+	}
+
+	// library consumers don't share the cli's region health
+	signer := cziClient.NewLambdaSigner(awsClient, blessConfig, "")
+	// credentials assumed with a custom token source are never cached, keep the cli's
+	signer.SetForgetCredentials(o.tokenSource == nil)
+	signer.SetLogger(o.logger)
+
+	return &Client{
+		blessConfig: blessConfig,
+		tokens:      tokens,
+		signer:      signer,
+		logger:      o.logger,
+	}, nil
+}
+
+// GetCertificate asks the CA to sign pubKey. pubKey is either a crypto.PublicKey
+// such as ed25519.PublicKey or an ssh.PublicKey that wraps one.
+func (c *Client) GetCertificate(ctx context.Context, pubKey crypto.PublicKey) (*ssh.Certificate, error) {
+	if sshKey, ok := pubKey.(ssh.CryptoPublicKey); ok {
+		pubKey = sshKey.CryptoPublicKey()
+	}
+
+	start := time.Now()
+	creds, token, err := c.tokens.Credentials(ctx)
+	if err != nil {
+		c.logger.WithError(err).Debug("could not get credentials")
+		return nil, err
+	}
+
+	signed, err := c.signer.Sign(ctx, creds, token, pubKey)
+	if err != nil {
+		c.logger.WithError(err).Debug("could not get certificate")
+		return nil, err
+	}
+	c.logger.WithFields(logrus.Fields{
+		"key_id":       signed.Certificate.KeyId,
+		"valid_before": time.Unix(int64(signed.Certificate.ValidBefore), 0),
+		"region":       signed.Region,
+		"took":         time.Since(start),
+	}).Debug("got certificate")
+	return signed.Certificate, nil
+}
+
+// tokenSourceProvider assumes roleARN with the id tokens from tokenSource
+func tokenSourceProvider(awsClient awsclient.Client, roleARN string, tokenSource TokenSource) cziClient.TokenProvider {
+	return cziClient.TokenProviderFunc(func(ctx context.Context) (aws.CredentialsProvider, *oidc.Token, error) {
+		token, err := tokenSource.Token(ctx)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "could not get oidc token")
+		}
+		creds := aws.NewCredentialsCache(awsclient.NewWebIdentityProvider(awsClient, roleARN, sessionName, token.IDToken))
+		return creds, &oidc.Token{IDToken: token.IDToken, AccessToken: token.AccessToken}, nil
+	})
+}
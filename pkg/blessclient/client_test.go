@@ -0,0 +1,160 @@
+package blessclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/ssh/sshtest"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// testEnv isolates the sdk from the environment it runs in
+func testEnv(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", path.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path.Join(dir, "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "us-west-2")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_CA_BUNDLE", "")
+}
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func respond(req *http.Request, body string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+}
+
+// fakeAWS answers sts with role credentials and the lambda with a certificate for pub
+func fakeAWS(r *require.Assertions, pub ed25519.PublicKey) http.RoundTripper {
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		body, err := ioutil.ReadAll(req.Body)
+		r.NoError(err)
+
+		switch {
+		case strings.HasPrefix(req.URL.Host, "sts."):
+			r.Contains(string(body), "WebIdentityToken=id-token")
+			return respond(req, `<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>
+<AccessKeyId>ASIA</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>session</SessionToken>
+<Expiration>2030-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`), nil
+		case strings.HasPrefix(req.URL.Host, "lambda."):
+			r.Contains(req.Header.Get("Authorization"), "ASIA/")
+			r.True(bytes.Contains(body, []byte(`"okta_identity":{"AccessToken":"access-token"}`)))
+			return respond(req, newCertPayload(r, pub)), nil
+		default:
+			return nil, errors.Errorf("unexpected request to %s", req.URL)
+		}
+	})
+}
+
+func newCertPayload(r *require.Assertions, pub ed25519.PublicKey) string {
+	cert := sshtest.NewCert(r, pub, time.Now().Add(-time.Minute), time.Now().Add(time.Hour))
+	payload, err := json.Marshal(map[string]interface{}{
+		"certificate": map[string]string{"cert": base64.StdEncoding.EncodeToString(cert.Marshal())},
+	})
+	r.NoError(err)
+	return string(payload)
+}
+
+func testConfig() *config.Config {
+	conf := &config.Config{}
+	conf.ClientConfig.RoleARN = "arn:aws:iam::123456789012:role/bless"
+	conf.LambdaConfig.FunctionName = "bless"
+	conf.LambdaConfig.FunctionVersion = aws.String("live")
+	conf.LambdaConfig.Regions = []config.Region{{AWSRegion: "us-west-2"}}
+	return conf
+}
+
+func staticTokens() TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		return &Token{IDToken: "id-token", AccessToken: "access-token"}, nil
+	})
+}
+
+func TestGetCertificate(t *testing.T) {
+	r := require.New(t)
+	testEnv(t)
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	r.NoError(err)
+
+	c, err := NewClient(testConfig(), WithTokenSource(staticTokens()), WithTransport(fakeAWS(r, pub)))
+	r.NoError(err)
+
+	cert, err := c.GetCertificate(context.Background(), pub)
+	r.NoError(err)
+	r.Equal(sshtest.KeyID, cert.KeyId)
+
+	// ssh public keys work too
+	sshPub, err := ssh.NewPublicKey(pub)
+	r.NoError(err)
+	cert, err = c.GetCertificate(context.Background(), sshPub)
+	r.NoError(err)
+	r.Equal(sshPub.Marshal(), cert.Key.Marshal())
+}
+
+func TestGetCertificateTokenSourceError(t *testing.T) {
+	r := require.New(t)
+	testEnv(t)
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	r.NoError(err)
+
+	tokens := TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		return nil, errors.New("no token for you")
+	})
+	c, err := NewClient(testConfig(), WithTokenSource(tokens), WithTransport(fakeAWS(r, pub)))
+	r.NoError(err)
+
+	_, err = c.GetCertificate(context.Background(), pub)
+	r.Error(err)
+	r.Contains(err.Error(), "no token for you")
+}
+
+func TestNewClientNilConfig(t *testing.T) {
+	r := require.New(t)
+	_, err := NewClient(nil)
+	r.Error(err)
+}
+
+func TestNewClientMinClientVersion(t *testing.T) {
+	r := require.New(t)
+	version, release := util.Version, util.Release
+	defer func() { util.Version, util.Release = version, release }()
+	util.Version, util.Release = "1.0.0", "true"
+
+	conf := testConfig()
+	conf.MinClientVersion = "1.1.0"
+	_, err := NewClient(conf)
+	versionErr := &config.ClientVersionError{}
+	r.True(errors.As(err, &versionErr))
+	r.Equal("1.1.0", versionErr.MinVersion)
+
+	conf.MinClientVersion = "1.0.0"
+	_, err = NewClient(conf)
+	r.NoError(err)
+}
@@ -0,0 +1,15 @@
+// Package blessclient requests SSH certificates from a bless CA so other
+// tools can mint certificates without shelling out to the blessclient cli.
+//
+//	conf, err := config.FromFile(config.DefaultConfigFile)
+//	...
+//	c, err := blessclient.NewClient(conf, blessclient.WithTokenSource(tokens))
+//	...
+//	cert, err := c.GetCertificate(ctx, pub)
+//
+// Compatibility: the exported API of this package follows semantic versioning
+// together with the blessclient releases. We only remove or change exported
+// identifiers in a new major version; minor versions may add options and fields.
+// The other packages under pkg are implementation details of the cli and carry
+// no such guarantee, with the exception of the config types accepted by NewClient.
+package blessclient
@@ -0,0 +1,67 @@
+package blessclient_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/chanzuckerberg/blessclient/pkg/blessclient"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+func ExampleClient_GetCertificate() {
+	conf, err := config.FromFile(config.DefaultConfigFile)
+	if err != nil {
+		panic(err)
+	}
+
+	// the CA signs a fresh key for every certificate
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	// without a token source we log in like the cli does
+	c, err := blessclient.NewClient(conf)
+	if err != nil {
+		panic(err)
+	}
+	cert, err := c.GetCertificate(context.Background(), pub)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%s", ssh.MarshalAuthorizedKey(cert))
+}
+
+func ExampleWithTokenSource() {
+	conf, err := config.FromFile(config.DefaultConfigFile)
+	if err != nil {
+		panic(err)
+	}
+
+	// for example a token injected by the ci system
+	tokens := blessclient.TokenSourceFunc(func(ctx context.Context) (*blessclient.Token, error) {
+		return &blessclient.Token{IDToken: os.Getenv("OIDC_TOKEN")}, nil
+	})
+	// there is no okta access token to send the CA, send it the id token instead
+	conf.ClientConfig.Identity = &config.IdentityConfig{Type: config.IdentityTypeOIDCIDToken}
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+
+	c, err := blessclient.NewClient(
+		conf,
+		blessclient.WithTokenSource(tokens),
+		blessclient.WithTransport(http.DefaultTransport),
+		blessclient.WithLogger(logger),
+	)
+	if err != nil {
+		panic(err)
+	}
+	_ = c
+}
@@ -0,0 +1,66 @@
+package blessclient
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Token is an oidc token from the identity provider the CA trusts
+type Token struct {
+	// IDToken is exchanged for aws credentials to invoke the CA
+	IDToken string
+	// AccessToken is sent to the CA as the identity, unless the
+	// config asks for a different identity type
+	AccessToken string
+}
+
+// TokenSource supplies oidc tokens
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// TokenSourceFunc adapts a function to a TokenSource
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+// Token calls f
+func (f TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return f(ctx)
+}
+
+// Option configures a Client
+type Option func(*options)
+
+type options struct {
+	tokenSource TokenSource
+	transport   http.RoundTripper
+	logger      logrus.FieldLogger
+}
+
+// WithTokenSource gets oidc tokens from tokenSource instead of logging in
+// interactively like the cli does. The default okta_access_token identity needs
+// Token.AccessToken, set client_config.identity to oidc_id_token if you only have an id token.
+func WithTokenSource(tokenSource TokenSource) Option {
+	return func(o *options) {
+		o.tokenSource = tokenSource
+	}
+}
+
+// WithTransport sends every aws request through transport. The proxy and
+// ca bundle in the aws config are ignored when it is set.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = transport
+	}
+}
+
+// WithLogger sends the log messages of requesting certificates, including failing over
+// between regions, to logger. They are discarded by default. Without WithTokenSource,
+// logging in and caching aws credentials still log through the standard logrus logger,
+// and the device flow prints its instructions to stderr.
+func WithLogger(logger logrus.FieldLogger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
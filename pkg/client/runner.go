@@ -0,0 +1,225 @@
+// Package client implements the certificate flow shared by the cli and other consumers:
+// check the key manager, get credentials, ask the CA to sign a key and store the result.
+package client
+
+import (
+	"context"
+	"crypto"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/chanzuckerberg/blessclient/pkg/bless"
+	cziSSH "github.com/chanzuckerberg/blessclient/pkg/ssh"
+	oidc "github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// TokenProvider gets an oidc token and the aws credentials we assumed with it
+type TokenProvider interface {
+	Credentials(ctx context.Context) (aws.CredentialsProvider, *oidc.Token, error)
+}
+
+// TokenProviderFunc adapts a function to a TokenProvider
+type TokenProviderFunc func(ctx context.Context) (aws.CredentialsProvider, *oidc.Token, error)
+
+// Credentials calls f
+func (f TokenProviderFunc) Credentials(ctx context.Context) (aws.CredentialsProvider, *oidc.Token, error) {
+	return f(ctx)
+}
+
+// Signed is a certificate and where it was signed
+type Signed struct {
+	Certificate *ssh.Certificate
+	// Region is the aws region of the CA that signed it, if any
+	Region string
+}
+
+// Signer asks the CA to sign a public key
+type Signer interface {
+	Sign(
+		ctx context.Context,
+		creds aws.CredentialsProvider,
+		token *oidc.Token,
+		publicKey crypto.PublicKey,
+	) (*Signed, error)
+}
+
+// AuthError means we could not get an oidc token or the aws credentials to call the CA with
+type AuthError struct {
+	Err error
+}
+
+// Error returns the string representation of this error
+func (e *AuthError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, bless.ErrAuthFailed) true
+func (e *AuthError) Is(target error) bool {
+	return target == bless.ErrAuthFailed
+}
+
+// KeyManagerError means the key manager failed, eg the ssh agent went away
+type KeyManagerError struct {
+	Err error
+}
+
+// Error returns the string representation of this error
+func (e *KeyManagerError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *KeyManagerError) Unwrap() error {
+	return e.Err
+}
+
+// Clock tells the time
+type Clock func() time.Time
+
+// RunOptions configure a single Run
+type RunOptions struct {
+	// Force requests a new certificate even if we have a valid one
+	Force bool
+}
+
+// Result describes the certificate we ended up with
+type Result struct {
+	// Refreshed is false if we already had a valid certificate
+	Refreshed   bool
+	Certificate *ssh.Certificate
+	ValidAfter  time.Time
+	ValidBefore time.Time
+	// Region that signed the certificate, empty if we didn't refresh it
+	Region string
+
+	// how long it took to get credentials, to get the certificate signed and to run overall
+	CredentialsDuration time.Duration
+	SignDuration        time.Duration
+	TotalDuration       time.Duration
+}
+
+// Runner makes sure a KeyManager has a valid certificate
+type Runner struct {
+	keyManager cziSSH.KeyManager
+	tokens     TokenProvider
+	signer     Signer
+	now        Clock
+}
+
+// NewRunner returns a new Runner. A nil clock uses time.Now.
+func NewRunner(keyManager cziSSH.KeyManager, tokens TokenProvider, signer Signer, clock Clock) *Runner {
+	if clock == nil {
+		clock = time.Now
+	}
+	return &Runner{
+		keyManager: keyManager,
+		tokens:     tokens,
+		signer:     signer,
+		now:        clock,
+	}
+}
+
+// Run requests a new certificate unless we already have a valid one
+func (r *Runner) Run(ctx context.Context, opts RunOptions) (*Result, error) {
+	start := r.now()
+
+	if !opts.Force {
+		cert, err := r.currentCertificate()
+		if err != nil {
+			return nil, err
+		}
+		if cert != nil {
+			logrus.Debug("fresh cert, nothing to do")
+			result := newResult(cert, false)
+			result.TotalDuration = r.now().Sub(start)
+			return result, nil
+		}
+	}
+
+	pub, priv, err := r.keyManager.GetKey()
+	if err != nil {
+		return nil, &KeyManagerError{Err: err}
+	}
+
+	credentialsStart := r.now()
+	creds, token, err := r.tokens.Credentials(ctx)
+	if err != nil {
+		return nil, &AuthError{Err: err}
+	}
+	if creds != nil {
+		// fail here rather than as a CA error if we can't assume the role
+		_, err = creds.Retrieve(ctx)
+		if err != nil {
+			return nil, &AuthError{Err: err}
+		}
+	}
+	credentialsDuration := r.now().Sub(credentialsStart)
+
+	signStart := r.now()
+	signed, err := r.signer.Sign(ctx, creds, token, pub)
+	if err != nil {
+		return nil, err
+	}
+	signDuration := r.now().Sub(signStart)
+
+	cert := signed.Certificate
+	if !time.Unix(int64(cert.ValidBefore), 0).After(r.now()) {
+		return nil, errors.New("CA returned an expired certificate")
+	}
+
+	err = r.keyManager.WriteKey(priv, cert)
+	if err != nil {
+		return nil, &KeyManagerError{Err: err}
+	}
+
+	hasCert, err := r.keyManager.HasValidCertificate()
+	if err != nil {
+		return nil, &KeyManagerError{Err: err}
+	}
+	if !hasCert {
+		return nil, errors.Errorf("wrote error to key manager, but could not fetch it back")
+	}
+
+	result := newResult(cert, true)
+	result.Region = signed.Region
+	result.CredentialsDuration = credentialsDuration
+	result.SignDuration = signDuration
+	result.TotalDuration = r.now().Sub(start)
+	return result, nil
+}
+
+// currentCertificate returns the valid certificate that lasts the longest, if any
+func (r *Runner) currentCertificate() (*ssh.Certificate, error) {
+	certs, err := r.keyManager.ListCertificates()
+	if err != nil {
+		return nil, &KeyManagerError{Err: err}
+	}
+
+	var current *ssh.Certificate
+	for _, cert := range certs {
+		if !time.Unix(int64(cert.ValidBefore), 0).After(r.now()) {
+			continue
+		}
+		if current == nil || cert.ValidBefore > current.ValidBefore {
+			current = cert
+		}
+	}
+	return current, nil
+}
+
+func newResult(cert *ssh.Certificate, refreshed bool) *Result {
+	return &Result{
+		Refreshed:   refreshed,
+		Certificate: cert,
+		ValidAfter:  time.Unix(int64(cert.ValidAfter), 0),
+		ValidBefore: time.Unix(int64(cert.ValidBefore), 0),
+	}
+}
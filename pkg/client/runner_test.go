@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/chanzuckerberg/blessclient/pkg/bless"
+	"github.com/chanzuckerberg/blessclient/pkg/ssh/sshtest"
+	oidc "github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// fakeKeyManager keeps a single key and its certificates in memory
+type fakeKeyManager struct {
+	pub   ed25519.PublicKey
+	priv  ed25519.PrivateKey
+	certs []*ssh.Certificate
+	now   Clock
+	// err fails every call, like an agent that went away
+	err error
+}
+
+func newFakeKeyManager(r *require.Assertions, now Clock) *fakeKeyManager {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	r.NoError(err)
+	return &fakeKeyManager{pub: pub, priv: priv, now: now}
+}
+
+func (m *fakeKeyManager) GetKey() (crypto.PublicKey, crypto.PrivateKey, error) {
+	return m.pub, m.priv, nil
+}
+
+func (m *fakeKeyManager) WriteKey(priv crypto.PrivateKey, cert *ssh.Certificate) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.certs = append(m.certs, cert)
+	return nil
+}
+
+func (m *fakeKeyManager) HasValidCertificate() (bool, error) {
+	certs, err := m.ListCertificates()
+	return len(certs) > 0, err
+}
+
+func (m *fakeKeyManager) ListCertificates() ([]*ssh.Certificate, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	valid := []*ssh.Certificate{}
+	for _, cert := range m.certs {
+		if time.Unix(int64(cert.ValidBefore), 0).After(m.now()) {
+			valid = append(valid, cert)
+		}
+	}
+	return valid, nil
+}
+
+func (m *fakeKeyManager) RemoveCertificates() (int, error) {
+	removed := len(m.certs)
+	m.certs = nil
+	return removed, nil
+}
+
+// fakeSigner signs every key with validFor
+type fakeSigner struct {
+	r        *require.Assertions
+	validFor time.Duration
+	now      Clock
+	signed   int
+	err      error
+}
+
+func (s *fakeSigner) Sign(
+	ctx context.Context,
+	creds aws.CredentialsProvider,
+	token *oidc.Token,
+	publicKey crypto.PublicKey,
+) (*Signed, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.r.Equal("access", token.AccessToken)
+	s.signed++
+	return &Signed{
+		Certificate: sshtest.NewCert(s.r, publicKey.(ed25519.PublicKey), s.now().Add(-time.Minute), s.now().Add(s.validFor)),
+		Region:      "us-west-2",
+	}, nil
+}
+
+func fakeTokens() TokenProvider {
+	return TokenProviderFunc(func(ctx context.Context) (aws.CredentialsProvider, *oidc.Token, error) {
+		creds := credentials.NewStaticCredentialsProvider("AKID", "secret", "")
+		return creds, &oidc.Token{AccessToken: "access"}, nil
+	})
+}
+
+func TestRunnerRun(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	now := time.Date(2030, 7, 20, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	manager := newFakeKeyManager(r, clock)
+	signer := &fakeSigner{r: r, validFor: time.Hour, now: clock}
+	runner := NewRunner(manager, fakeTokens(), signer, clock)
+
+	result, err := runner.Run(ctx, RunOptions{})
+	r.NoError(err)
+	r.True(result.Refreshed)
+	r.Equal(now.Add(time.Hour), result.ValidBefore.UTC())
+	r.Equal("us-west-2", result.Region)
+	r.Len(manager.certs, 1)
+
+	// the certificate is still fresh
+	result, err = runner.Run(ctx, RunOptions{})
+	r.NoError(err)
+	r.False(result.Refreshed)
+	r.Equal(manager.certs[0], result.Certificate)
+	r.Empty(result.Region)
+	r.Equal(1, signer.signed)
+
+	// unless we force it
+	result, err = runner.Run(ctx, RunOptions{Force: true})
+	r.NoError(err)
+	r.True(result.Refreshed)
+	r.Equal(2, signer.signed)
+
+	// or it expires
+	now = now.Add(2 * time.Hour)
+	result, err = runner.Run(ctx, RunOptions{})
+	r.NoError(err)
+	r.True(result.Refreshed)
+	r.Equal(3, signer.signed)
+}
+
+func TestRunnerRejectsExpiredCertificate(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Now()
+	clock := func() time.Time { return now }
+	manager := newFakeKeyManager(r, clock)
+	signer := &fakeSigner{r: r, validFor: -time.Minute, now: clock}
+
+	_, err := NewRunner(manager, fakeTokens(), signer, clock).Run(context.Background(), RunOptions{})
+	r.Error(err)
+	r.Contains(err.Error(), "expired")
+	r.Empty(manager.certs)
+}
+
+func TestRunnerSignerError(t *testing.T) {
+	r := require.New(t)
+
+	manager := newFakeKeyManager(r, time.Now)
+	signer := &fakeSigner{r: r, err: errors.New("denied")}
+
+	_, err := NewRunner(manager, fakeTokens(), signer, nil).Run(context.Background(), RunOptions{})
+	r.EqualError(err, "denied")
+	r.Empty(manager.certs)
+}
+
+func TestRunnerAuthError(t *testing.T) {
+	r := require.New(t)
+
+	manager := newFakeKeyManager(r, time.Now)
+	signer := &fakeSigner{r: r, validFor: time.Hour, now: time.Now}
+
+	tokens := TokenProviderFunc(func(ctx context.Context) (aws.CredentialsProvider, *oidc.Token, error) {
+		return nil, nil, errors.New("login failed")
+	})
+	_, err := NewRunner(manager, tokens, signer, nil).Run(context.Background(), RunOptions{})
+	var authErr *AuthError
+	r.True(errors.As(err, &authErr))
+	r.EqualError(err, "login failed")
+	r.True(errors.Is(err, bless.ErrAuthFailed))
+	r.Contains(bless.Remediation(err), "client_config.role_arn")
+
+	// credentials we can't retrieve are an auth error too
+	tokens = TokenProviderFunc(func(ctx context.Context) (aws.CredentialsProvider, *oidc.Token, error) {
+		creds := aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{}, errors.New("AccessDenied: not authorized to assume role")
+		})
+		return creds, &oidc.Token{AccessToken: "access"}, nil
+	})
+	_, err = NewRunner(manager, tokens, signer, nil).Run(context.Background(), RunOptions{})
+	r.True(errors.As(err, &authErr))
+	r.Equal(0, signer.signed)
+}
+
+func TestRunnerKeyManagerError(t *testing.T) {
+	r := require.New(t)
+
+	manager := newFakeKeyManager(r, time.Now)
+	manager.err = errors.New("could not list agent keys: agent went away")
+	signer := &fakeSigner{r: r, validFor: time.Hour, now: time.Now}
+
+	_, err := NewRunner(manager, fakeTokens(), signer, nil).Run(context.Background(), RunOptions{})
+	var keyManagerErr *KeyManagerError
+	r.True(errors.As(err, &keyManagerErr))
+	r.EqualError(err, "could not list agent keys: agent went away")
+
+	// writing the certificate too
+	_, err = NewRunner(manager, fakeTokens(), signer, nil).Run(context.Background(), RunOptions{Force: true})
+	r.True(errors.As(err, &keyManagerErr))
+	r.Equal(1, signer.signed)
+}
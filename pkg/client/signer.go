@@ -0,0 +1,196 @@
+package client
+
+import (
+	"context"
+	"crypto"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/chanzuckerberg/blessclient/pkg/awsclient"
+	"github.com/chanzuckerberg/blessclient/pkg/bless"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/webidentity"
+	oidc "github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// LambdaSigner requests certificates from the bless lambda,
+// failing over between the configured regions
+type LambdaSigner struct {
+	awsClient   awsclient.Client
+	blessConfig *config.Config
+	healthPath  string
+	// forgetCredentials drops the cached aws credentials when the CA denies access
+	forgetCredentials bool
+	logger            logrus.FieldLogger
+}
+
+// NewLambdaSigner returns a new LambdaSigner. It remembers
+// unhealthy regions in healthPath, if set.
+func NewLambdaSigner(awsClient awsclient.Client, blessConfig *config.Config, healthPath string) *LambdaSigner {
+	return &LambdaSigner{
+		awsClient:         awsClient,
+		blessConfig:       blessConfig,
+		healthPath:        healthPath,
+		forgetCredentials: true,
+		logger:            logrus.StandardLogger(),
+	}
+}
+
+// SetForgetCredentials sets whether Sign drops the cached aws credentials for the
+// configured role when the CA denies access, true by default.
+// Turn it off when the credentials don't come from that cache.
+func (s *LambdaSigner) SetForgetCredentials(forget bool) {
+	s.forgetCredentials = forget
+}
+
+// SetLogger sets where Sign logs, including failing over between regions.
+// The standard logger by default.
+func (s *LambdaSigner) SetLogger(logger logrus.FieldLogger) {
+	s.logger = logger
+}
+
+// Sign requests a certificate for publicKey with the configured identity
+func (s *LambdaSigner) Sign(
+	ctx context.Context,
+	creds aws.CredentialsProvider,
+	token *oidc.Token,
+	publicKey crypto.PublicKey,
+) (*Signed, error) {
+	identity, err := getIdentity(ctx, s.awsClient, s.blessConfig, token)
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := s.regionalGetCert(ctx, creds, identity, publicKey)
+	if s.forgetCredentials && errors.Is(err, bless.ErrAccessDenied) {
+		// we might have been denied because of stale cached credentials
+		forgetErr := awsclient.ForgetCredentials(
+			s.blessConfig.ClientConfig.RoleARN,
+			s.blessConfig.ClientConfig.OIDCIssuerURL,
+		)
+		if forgetErr != nil {
+			s.logger.WithError(forgetErr).Debug("could not forget cached aws credentials")
+		}
+	}
+	return signed, err
+}
+
+func (s *LambdaSigner) regionalGetCert(
+	ctx context.Context,
+	creds aws.CredentialsProvider,
+	identity *bless.Identity,
+	publicKey crypto.PublicKey,
+) (*Signed, error) {
+	var health *bless.RegionHealth
+	if s.healthPath != "" {
+		var err error
+		health, err = bless.LoadRegionHealth(s.healthPath)
+		if err != nil {
+			s.logger.WithError(err).Debug("could not load region health, ignoring it")
+		}
+	}
+	persist := health != nil
+	if health == nil {
+		// don't remember anything
+		health = bless.NewRegionHealth("")
+	}
+	health.SetLogger(s.logger)
+
+	// hedged requests run concurrently, so remember which region signed which cert
+	var mu sync.Mutex
+	regions := map[*ssh.Certificate]string{}
+
+	client := bless.NewOIDC(s.awsClient, &s.blessConfig.LambdaConfig)
+	client.SetLogger(s.logger)
+	failover := bless.NewFailover(&s.blessConfig.LambdaConfig, health)
+	failover.SetLogger(s.logger)
+	cert, err := failover.Do(ctx, func(ctx context.Context, region config.Region) (*ssh.Certificate, error) {
+		cert, err := client.RequestCert(
+			ctx,
+			region,
+			creds,
+			&bless.SigningRequest{
+				PublicKeyToSign: bless.NewPublicKeyToSign(publicKey),
+				Identity:        *identity,
+			},
+		)
+		if err == nil {
+			mu.Lock()
+			regions[cert] = region.AWSRegion
+			mu.Unlock()
+		}
+		return cert, err
+	})
+
+	if persist {
+		persistErr := health.Persist()
+		if persistErr != nil {
+			s.logger.WithError(persistErr).Debug("could not persist region health, ignoring error")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return &Signed{Certificate: cert, Region: regions[cert]}, nil
+}
+
+// getIdentity builds the identity assertion configured in blessConfig
+func getIdentity(
+	ctx context.Context,
+	presigner bless.CallerIdentityPresigner,
+	blessConfig *config.Config,
+	token *oidc.Token,
+) (*bless.Identity, error) {
+	identityConfig, err := blessConfig.ClientConfig.GetIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	switch identityConfig.Type {
+	case config.IdentityTypeOIDCIDToken:
+		return &bless.Identity{
+			OIDCIDToken: &bless.OIDCIDTokenInput{IDToken: token.IDToken},
+		}, nil
+	case config.IdentityTypeGithubActions:
+		idToken, err := webidentity.FromGithubActions(ctx, nil, identityConfig.Audience)
+		if err != nil {
+			return nil, err
+		}
+		return &bless.Identity{
+			GithubActionsToken: &bless.GithubActionsTokenInput{IDToken: idToken},
+		}, nil
+	case config.IdentityTypeKubernetes:
+		tokenPath := identityConfig.TokenPath
+		if tokenPath == "" {
+			tokenPath = webidentity.DefaultKubernetesTokenPath
+		}
+		saToken, err := webidentity.FromFile(tokenPath)
+		if err != nil {
+			return nil, err
+		}
+		return &bless.Identity{
+			KubernetesServiceAccount: &bless.KubernetesServiceAccountInput{Token: saToken},
+		}, nil
+	case config.IdentityTypeAWSCallerIdentity:
+		callerIdentity, err := bless.NewAWSCallerIdentityInput(ctx, presigner)
+		if err != nil {
+			return nil, err
+		}
+		return &bless.Identity{AWSCallerIdentity: callerIdentity}, nil
+	default:
+		if token.AccessToken == "" {
+			return nil, errors.Errorf(
+				"identity type %s requires an interactive login, set client_config.identity.type to use headless mode",
+				identityConfig.Type)
+		}
+		return &bless.Identity{
+			OktaAccessToken: &bless.OktaAccessTokenInput{AccessToken: token.AccessToken},
+		}, nil
+	}
+}
@@ -0,0 +1,195 @@
+package client
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	lambdatypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/chanzuckerberg/blessclient/pkg/awsclient"
+	"github.com/chanzuckerberg/blessclient/pkg/awsclient/mocks"
+	"github.com/chanzuckerberg/blessclient/pkg/bless"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/ssh/sshtest"
+	oidc "github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	logrustest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func TestGetIdentity(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	token := &oidc.Token{IDToken: "id", AccessToken: "access"}
+
+	conf := &config.Config{}
+	identity, err := getIdentity(ctx, nil, conf, token)
+	r.NoError(err)
+	r.Equal("access", identity.OktaAccessToken.AccessToken)
+
+	conf.ClientConfig.Identity = &config.IdentityConfig{Type: config.IdentityTypeOIDCIDToken}
+	identity, err = getIdentity(ctx, nil, conf, token)
+	r.NoError(err)
+	r.Nil(identity.OktaAccessToken)
+	r.Equal("id", identity.OIDCIDToken.IDToken)
+}
+
+func TestGetIdentityHeadlessNeedsNonOktaIdentity(t *testing.T) {
+	r := require.New(t)
+
+	// headless mode only has an id token
+	_, err := getIdentity(context.Background(), nil, &config.Config{}, &oidc.Token{IDToken: "id"})
+	r.Error(err)
+	r.Contains(err.Error(), "requires an interactive login")
+}
+
+// newSignedCertPayload returns a lambda response carrying a certificate for pub
+func newSignedCertPayload(r *require.Assertions, pub ed25519.PublicKey) []byte {
+	cert := sshtest.NewCert(r, pub, time.Now().Add(-time.Minute), time.Now().Add(time.Hour))
+	payload, err := json.Marshal(map[string]interface{}{
+		"certificate": map[string]string{"cert": base64.StdEncoding.EncodeToString(cert.Marshal())},
+	})
+	r.NoError(err)
+	return payload
+}
+
+func testRegionalConfig(regions ...string) *config.Config {
+	conf := &config.Config{}
+	conf.LambdaConfig.FunctionName = "bless"
+	conf.LambdaConfig.FunctionVersion = aws.String("live")
+	// no retries so tests don't sleep
+	conf.LambdaConfig.Failover = &config.FailoverConfig{Retries: -1}
+	for _, region := range regions {
+		conf.LambdaConfig.Regions = append(conf.LambdaConfig.Regions, config.Region{AWSRegion: region})
+	}
+	return conf
+}
+
+func TestLambdaSignerSign(t *testing.T) {
+	r := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	r.NoError(err)
+	creds := aws.AnonymousCredentials{}
+	token := &oidc.Token{AccessToken: "access"}
+
+	awsClient := mocks.NewMockClient(ctrl)
+	awsClient.EXPECT().InvokeWithQualifier(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, input *awsclient.InvokeInput) ([]byte, error) {
+			r.Equal("us-west-2", input.Region)
+			r.Equal("bless", input.FunctionName)
+			r.Equal("live", *input.Qualifier)
+			r.Equal(creds, input.Credentials)
+			r.Contains(string(input.Payload), `"okta_identity":{"AccessToken":"access"}`)
+			return newSignedCertPayload(r, pub), nil
+		})
+
+	healthPath := path.Join(t.TempDir(), "region_health.json")
+	signer := NewLambdaSigner(awsClient, testRegionalConfig("us-west-2"), healthPath)
+	signed, err := signer.Sign(context.Background(), creds, token, pub)
+	r.NoError(err)
+	r.Equal(sshtest.KeyID, signed.Certificate.KeyId)
+	r.Equal("us-west-2", signed.Region)
+}
+
+func TestLambdaSignerFailover(t *testing.T) {
+	r := require.New(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	r.NoError(err)
+	token := &oidc.Token{AccessToken: "access"}
+
+	awsClient := mocks.NewMockClient(ctrl)
+	gomock.InOrder(
+		awsClient.EXPECT().InvokeWithQualifier(gomock.Any(), regionIs("us-west-2")).
+			Return(nil, &lambdatypes.ServiceException{Message: aws.String("oops")}),
+		awsClient.EXPECT().InvokeWithQualifier(gomock.Any(), regionIs("us-east-1")).
+			Return(newSignedCertPayload(r, pub), nil),
+	)
+
+	logger, logs := logrustest.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	healthPath := path.Join(t.TempDir(), "region_health.json")
+	conf := testRegionalConfig("us-west-2", "us-east-1")
+	signer := NewLambdaSigner(awsClient, conf, healthPath)
+	signer.SetLogger(logger)
+	signed, err := signer.Sign(context.Background(), nil, token, pub)
+	r.NoError(err)
+	r.Equal("us-east-1", signed.Region)
+
+	// failing over logs through the signer's logger
+	messages := []string{}
+	for _, entry := range logs.AllEntries() {
+		messages = append(messages, entry.Message)
+	}
+	r.Contains(messages, "Attempting to get cert from region us-east-1")
+
+	// we remember that us-west-2 failed
+	health, err := bless.LoadRegionHealth(healthPath)
+	r.NoError(err)
+	r.False(health.Regions["us-west-2"].LastFailure.IsZero())
+}
+
+func TestLambdaSignerAccessDenied(t *testing.T) {
+	r := require.New(t)
+	keyring.MockInit()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	r.NoError(err)
+	token := &oidc.Token{AccessToken: "access"}
+
+	awsClient := mocks.NewMockClient(ctrl)
+	// no point in asking the other region
+	awsClient.EXPECT().InvokeWithQualifier(gomock.Any(), regionIs("us-west-2")).
+		Return([]byte(`{"errorType": "AccessDenied", "errorMessage": "not in group"}`), nil).
+		Times(2)
+
+	healthPath := path.Join(t.TempDir(), "region_health.json")
+	conf := testRegionalConfig("us-west-2", "us-east-1")
+	cacheKey := "sts " + conf.ClientConfig.RoleARN + " " + conf.ClientConfig.OIDCIssuerURL
+	r.NoError(keyring.Set("blessclient", cacheKey, "{}"))
+
+	// credentials from elsewhere, the cache stays
+	signer := NewLambdaSigner(awsClient, conf, healthPath)
+	signer.SetForgetCredentials(false)
+	_, err = signer.Sign(context.Background(), nil, token, pub)
+	r.Error(err)
+	r.True(errors.Is(err, bless.ErrAccessDenied))
+	_, err = keyring.Get("blessclient", cacheKey)
+	r.NoError(err)
+
+	// the cached credentials might be stale, forget them
+	_, err = NewLambdaSigner(awsClient, conf, healthPath).Sign(context.Background(), nil, token, pub)
+	r.Error(err)
+	r.True(errors.Is(err, bless.ErrAccessDenied))
+	_, err = keyring.Get("blessclient", cacheKey)
+	r.Equal(keyring.ErrNotFound, err)
+}
+
+// regionIs matches InvokeInputs for region
+type regionIs string
+
+func (m regionIs) Matches(x interface{}) bool {
+	input, ok := x.(*awsclient.InvokeInput)
+	return ok && input.Region == string(m)
+}
+
+func (m regionIs) String() string {
+	return "invoked in " + string(m)
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/chanzuckerberg/blessclient/pkg/awsclient"
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/login"
+	"github.com/chanzuckerberg/blessclient/pkg/webidentity"
+	oidc "github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+)
+
+const (
+	defaultSessionName = "blessclient"
+)
+
+// InteractiveTokens logs in if needed and assumes
+// roleARN with the resulting oidc token
+func InteractiveTokens(awsClient awsclient.Client, roleARN string, loginConfig *login.Config) TokenProvider {
+	return TokenProviderFunc(func(ctx context.Context) (aws.CredentialsProvider, *oidc.Token, error) {
+		token, err := login.GetToken(ctx, loginConfig)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		sessionName := token.Claims.Email
+		if sessionName == "" {
+			sessionName = defaultSessionName
+		}
+		provider := awsclient.NewWebIdentityProvider(awsClient, roleARN, sessionName, token.IDToken)
+		// the role lasts much longer than a single run
+		return awsclient.NewCachedCredentials(provider, roleARN, loginConfig.IssuerURL), token, nil
+	})
+}
+
+// HeadlessTokens reads an oidc token from a non-interactive source
+// and assumes the configured role with it. It never opens a browser.
+func HeadlessTokens(awsClient awsclient.Client, blessConfig *config.Config, opts webidentity.Options) TokenProvider {
+	return TokenProviderFunc(func(ctx context.Context) (aws.CredentialsProvider, *oidc.Token, error) {
+		identityConfig, err := blessConfig.ClientConfig.GetIdentity()
+		if err != nil {
+			return nil, nil, err
+		}
+		opts.Audience = identityConfig.Audience
+
+		webToken, err := webidentity.Fetch(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		creds := aws.NewCredentialsCache(awsclient.NewWebIdentityProvider(
+			awsClient,
+			blessConfig.ClientConfig.RoleARN,
+			defaultSessionName,
+			webToken,
+		))
+		// fail fast if we can't assume the role
+		_, err = creds.Retrieve(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		return creds, &oidc.Token{IDToken: webToken}, nil
+	})
+}
@@ -1,10 +1,15 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"time"
 
+	"github.com/blang/semver"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
 	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -17,12 +22,33 @@ const (
 
 	// DefaultConfigFile is the default file where blessclient will look for its config
 	DefaultConfigFile = "~/.blessclient/config.yml"
+
+	// DefaultRegionHealthFile is where blessclient remembers how each region has been doing
+	DefaultRegionHealthFile = "~/.blessclient/region_health.json"
+
+	// DefaultUpgradeCheckFile is where blessclient remembers the latest release it found
+	DefaultUpgradeCheckFile = "~/.blessclient/upgrade_check.json"
+
+	// DefaultLogFile is where blessclient keeps debug logs, moved aside as it grows
+	DefaultLogFile = "~/.blessclient/logs/blessclient.log"
+
+	// DefaultRegionCooldown is how long we skip a region after it fails
+	DefaultRegionCooldown = 5 * time.Minute
+	// DefaultRegionTimeout bounds a single lambda invocation
+	DefaultRegionTimeout = 15 * time.Second
+	// DefaultRegionRetries is how many times we retry a region on transient errors
+	DefaultRegionRetries = 2
+	// DefaultFailoverTimeout bounds a certificate request across all regions
+	DefaultFailoverTimeout = time.Minute
 )
 
 // Config is a blessclient config
 type Config struct {
 	// Version versions this config
 	Version int `yaml:"version"`
+	// MinClientVersion is the oldest blessclient that works with this config,
+	// eg because the CA changed what it expects from clients
+	MinClientVersion string `yaml:"min_client_version,omitempty"`
 
 	// ClientConfig has configuration related to blessclient
 	ClientConfig ClientConfig `yaml:"client_config"`
@@ -30,6 +56,36 @@ type Config struct {
 	LambdaConfig LambdaConfig `yaml:"lambda_config"`
 	// For convenience, you can bundle an ~/.ssh/config template here
 	SSHConfig *SSHConfig `yaml:"ssh_config,omitempty"`
+	// AWS configures how we talk to sts and lambda
+	AWS *AWSConfig `yaml:"aws,omitempty"`
+	// Upgrade configures where blessclient looks for new versions
+	Upgrade *UpgradeConfig `yaml:"upgrade,omitempty"`
+}
+
+// UpgradeConfig configures where blessclient looks for new versions
+type UpgradeConfig struct {
+	// ManifestURL is a release manifest go-getter can fetch, defaults to GitHub releases
+	ManifestURL string `yaml:"manifest_url,omitempty"`
+	// TrustedKeys are extra ssh public keys allowed to sign releases, in authorized_keys format
+	TrustedKeys []string `yaml:"trusted_keys,omitempty"`
+	// Notice makes run mention newer versions once a day, off by default
+	Notice bool `yaml:"notice,omitempty"`
+}
+
+// AWSConfig configures the aws clients
+type AWSConfig struct {
+	// Profile is the aws profile to load the region and base credentials from
+	Profile string `yaml:"profile,omitempty"`
+	// Partition is the aws partition, eg aws-us-gov. Every region we use must be in it.
+	Partition string `yaml:"partition,omitempty"`
+	// STSEndpoint overrides the sts endpoint, eg a vpc or fips endpoint
+	STSEndpoint string `yaml:"sts_endpoint,omitempty"`
+	// LambdaEndpoints overrides the lambda endpoint per region
+	LambdaEndpoints map[string]string `yaml:"lambda_endpoints,omitempty"`
+	// CABundle is a pem file with extra CAs to trust, eg for a tls intercepting proxy
+	CABundle string `yaml:"ca_bundle,omitempty"`
+	// ProxyURL sends every aws request through this proxy
+	ProxyURL string `yaml:"proxy_url,omitempty"`
 }
 
 type ClientConfig struct {
@@ -37,8 +93,61 @@ type ClientConfig struct {
 	OIDCClientID string `yaml:"oidc_client_id"`
 	// Oidc issuer url: eg: foo.okta.com
 	OIDCIssuerURL string `yaml:"oidc_issuer_url"`
+	// OIDCScopes to request, defaults to openid, offline_access, email and groups
+	OIDCScopes []string `yaml:"oidc_scopes,omitempty"`
+	// OIDCAudience is sent as the audience parameter when logging in
+	OIDCAudience string `yaml:"oidc_audience,omitempty"`
+	// OIDCRedirectPort pins the localhost port the browser login redirects to,
+	// so it can be allow-listed as a redirect uri
+	OIDCRedirectPort int `yaml:"oidc_redirect_port,omitempty"`
 	// RoleARN is the aws role arn to assume to invoke the CA lambda
 	RoleARN string `yaml:"role_arn"`
+	// Identity selects the identity assertion sent to the CA
+	Identity *IdentityConfig `yaml:"identity,omitempty"`
+}
+
+// IdentityType is a kind of identity assertion the CA accepts
+type IdentityType string
+
+// Identity assertions we know how to send
+const (
+	IdentityTypeOktaAccessToken   IdentityType = "okta_access_token"
+	IdentityTypeOIDCIDToken       IdentityType = "oidc_id_token"
+	IdentityTypeGithubActions     IdentityType = "github_actions"
+	IdentityTypeAWSCallerIdentity IdentityType = "aws_caller_identity"
+	IdentityTypeKubernetes        IdentityType = "kubernetes"
+)
+
+// IdentityConfig configures the identity assertion sent to the CA
+type IdentityConfig struct {
+	// Type of identity assertion, defaults to okta_access_token
+	Type IdentityType `yaml:"type"`
+	// Audience to request github_actions tokens for
+	Audience string `yaml:"audience,omitempty"`
+	// TokenPath to read kubernetes service account tokens from
+	TokenPath string `yaml:"token_path,omitempty"`
+}
+
+// GetIdentity returns the identity config with defaults filled in
+func (c *ClientConfig) GetIdentity() (IdentityConfig, error) {
+	identity := IdentityConfig{}
+	if c.Identity != nil {
+		identity = *c.Identity
+	}
+	if identity.Type == "" {
+		identity.Type = IdentityTypeOktaAccessToken
+	}
+
+	switch identity.Type {
+	case IdentityTypeOktaAccessToken,
+		IdentityTypeOIDCIDToken,
+		IdentityTypeGithubActions,
+		IdentityTypeAWSCallerIdentity,
+		IdentityTypeKubernetes:
+		return identity, nil
+	default:
+		return identity, errors.Errorf("unknown identity type %s", identity.Type)
+	}
 }
 
 // Region is an aws region that contains an aws lambda
@@ -55,6 +164,50 @@ type LambdaConfig struct {
 	FunctionVersion *string `yaml:"function_version,omitempty"`
 	// bless lambda regions
 	Regions []Region `yaml:"regions,omitempty"`
+	// Failover controls how we pick between regions
+	Failover *FailoverConfig `yaml:"failover,omitempty"`
+}
+
+// FailoverConfig controls how we fail over between lambda regions
+type FailoverConfig struct {
+	// Cooldown is how long to skip a region after it failed. Negative disables it.
+	Cooldown time.Duration `yaml:"cooldown,omitempty"`
+	// HedgeDelay, if set, races the first two regions
+	// starting the second one after this delay.
+	HedgeDelay time.Duration `yaml:"hedge_delay,omitempty"`
+	// OrderByLatency tries the regions with the lowest measured latency first
+	OrderByLatency bool `yaml:"order_by_latency,omitempty"`
+	// RegionTimeout bounds a single lambda invocation
+	RegionTimeout time.Duration `yaml:"region_timeout,omitempty"`
+	// Retries is how many times we retry a region on transient errors
+	// before moving on to the next one. Negative disables retries.
+	Retries int `yaml:"retries,omitempty"`
+	// Timeout bounds the whole certificate request across all regions
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// GetFailover returns the failover config with defaults filled in
+func (l *LambdaConfig) GetFailover() FailoverConfig {
+	failover := FailoverConfig{}
+	if l.Failover != nil {
+		failover = *l.Failover
+	}
+	if failover.Cooldown == 0 {
+		failover.Cooldown = DefaultRegionCooldown
+	}
+	if failover.RegionTimeout <= 0 {
+		failover.RegionTimeout = DefaultRegionTimeout
+	}
+	if failover.Retries == 0 {
+		failover.Retries = DefaultRegionRetries
+	}
+	if failover.Retries < 0 {
+		failover.Retries = 0
+	}
+	if failover.Timeout <= 0 {
+		failover.Timeout = DefaultFailoverTimeout
+	}
+	return failover
 }
 
 // DefaultConfig generates a config with some defaults
@@ -87,6 +240,60 @@ func FromFile(confPath string) (*Config, error) {
 	return conf, nil
 }
 
+// ClientVersionError means this blessclient is too old for the config
+type ClientVersionError struct {
+	Version    string
+	MinVersion string
+}
+
+// Error returns the string representation of this error
+func (e *ClientVersionError) Error() string {
+	return fmt.Sprintf(
+		"this config requires blessclient %s or newer but you have %s, see https://github.com/chanzuckerberg/blessclient#install",
+		e.MinVersion, e.Version)
+}
+
+// CheckClientVersion errors if this blessclient is older than MinClientVersion.
+// Programs using blessclient as a library are checked against the module version they
+// were built with. Development builds are never too old so they work with any config.
+func (c *Config) CheckClientVersion() error {
+	version, err := util.VersionString()
+	if err != nil {
+		return err
+	}
+	release := util.IsRelease()
+	if !release {
+		moduleVersion, ok := util.ModuleVersion()
+		if ok {
+			version, release = moduleVersion, true
+		}
+	}
+	return c.checkClientVersion(version, release)
+}
+
+func (c *Config) checkClientVersion(version string, release bool) error {
+	if c.MinClientVersion == "" {
+		return nil
+	}
+	minVersion, err := semver.ParseTolerant(c.MinClientVersion)
+	if err != nil {
+		return errors.Wrapf(err, "could not parse min_client_version %s", c.MinClientVersion)
+	}
+	if !release {
+		log.Debugf("development build %s, skipping the min_client_version %s check", version, minVersion)
+		return nil
+	}
+
+	current, err := semver.Parse(version)
+	if err != nil {
+		return errors.Wrapf(err, "could not parse blessclient version %s", version)
+	}
+	if current.LT(minVersion) {
+		return &ClientVersionError{Version: current.String(), MinVersion: minVersion.String()}
+	}
+	return nil
+}
+
 // Persist persists a config to disk
 func (c *Config) Persist(configPath string) error {
 	configPath, err := GetOrCreateConfigPath(configPath)
@@ -107,6 +314,29 @@ func (c *Config) Persist(configPath string) error {
 	return nil
 }
 
+// Profiles returns every blessclient config next to configPath, configPath first.
+// Each config is a profile with its own oidc client and CA.
+func Profiles(configPath string) ([]string, error) {
+	expandedConfigFile, err := homedir.Expand(configPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not expand %s", configPath)
+	}
+
+	profiles := []string{expandedConfigFile}
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(path.Join(path.Dir(expandedConfigFile), pattern))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not list configs next to %s", configPath)
+		}
+		for _, match := range matches {
+			if match != expandedConfigFile {
+				profiles = append(profiles, match)
+			}
+		}
+	}
+	return profiles, nil
+}
+
 func GetOrCreateConfigPath(configPath string) (string, error) {
 	expandedConfigFile, err := homedir.Expand(configPath)
 	if err != nil {
@@ -5,12 +5,15 @@ import (
 	"io/ioutil"
 	"net/http/httptest"
 	"os"
+	"path"
 	"testing"
 
 	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
 	cziAws "github.com/chanzuckerberg/go-misc/aws"
 	cziAWSMocks "github.com/chanzuckerberg/go-misc/aws/mocks"
 	"github.com/golang/mock/gomock"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	yaml "gopkg.in/yaml.v2"
@@ -96,6 +99,79 @@ func TestFromFileMissing(t *testing.T) {
 	r.Nil(c)
 }
 
+func TestGetIdentity(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	c := &config.ClientConfig{}
+	identity, err := c.GetIdentity()
+	r.NoError(err)
+	r.Equal(config.IdentityTypeOktaAccessToken, identity.Type)
+
+	c.Identity = &config.IdentityConfig{Type: config.IdentityTypeKubernetes, TokenPath: "/token"}
+	identity, err = c.GetIdentity()
+	r.NoError(err)
+	r.Equal(config.IdentityTypeKubernetes, identity.Type)
+	r.Equal("/token", identity.TokenPath)
+
+	c.Identity = &config.IdentityConfig{Type: "carrier_pigeon"}
+	_, err = c.GetIdentity()
+	r.Error(err)
+	r.Contains(err.Error(), "unknown identity type carrier_pigeon")
+}
+
+func TestCheckClientVersion(t *testing.T) {
+	r := require.New(t)
+	oldVersion, oldRelease, oldDirty := util.Version, util.Release, util.Dirty
+	defer func() {
+		util.Version, util.Release, util.Dirty = oldVersion, oldRelease, oldDirty
+	}()
+	util.Version, util.Release, util.Dirty = "1.2.0", "true", "false"
+
+	c := config.DefaultConfig()
+	r.NoError(c.CheckClientVersion())
+
+	c.MinClientVersion = "v1.2.0"
+	r.NoError(c.CheckClientVersion())
+
+	c.MinClientVersion = "1.3.0"
+	err := c.CheckClientVersion()
+	r.Error(err)
+	versionErr := &config.ClientVersionError{}
+	r.True(errors.As(err, &versionErr))
+	r.Equal("1.3.0", versionErr.MinVersion)
+	r.Equal("1.2.0", versionErr.Version)
+	r.Contains(err.Error(), "blessclient#install")
+
+	// development builds work with any config
+	util.Release = "false"
+	r.NoError(c.CheckClientVersion())
+
+	c.MinClientVersion = "latest"
+	err = c.CheckClientVersion()
+	r.Error(err)
+	r.Contains(err.Error(), "could not parse min_client_version latest")
+}
+
+func TestProfiles(t *testing.T) {
+	r := require.New(t)
+	dir, err := ioutil.TempDir("", "blessclient-profiles")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"work.yml", "config.yml", "other.yaml", "region_health.json"} {
+		r.NoError(ioutil.WriteFile(path.Join(dir, name), []byte{}, 0644))
+	}
+
+	profiles, err := config.Profiles(path.Join(dir, "config.yml"))
+	r.NoError(err)
+	r.Equal([]string{
+		path.Join(dir, "config.yml"),
+		path.Join(dir, "work.yml"),
+		path.Join(dir, "other.yaml"),
+	}, profiles)
+}
+
 func TestConfigSuite(t *testing.T) {
 	suite.Run(t, new(TestSuite))
 }
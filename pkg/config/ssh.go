@@ -14,7 +14,7 @@ const (
 	sshConfigTemplate = `
 ######### Generated by blessclient v{{ version }} at {{ now }}#############
 {{ range .Bastions }}{{ $bastion := . }}
-Match OriginalHost  {{ .Pattern }} exec "{{ .SSHExecCommand.String }}"
+{{ template "match" . }}
 	User {{ .User }}
 
 Host {{ .Pattern }}
@@ -35,6 +35,9 @@ Host {{ .Pattern }}
 {{- end -}}
 {{ end }}{{ end }}
 `
+
+	// sshMatchTemplate makes ssh run blessclient before connecting to a bastion
+	sshMatchTemplate = `Match OriginalHost  {{ .Pattern }} exec "{{ .SSHExecCommand.String }}"`
 )
 
 func now() string {
@@ -50,13 +53,9 @@ type SSHConfig struct {
 
 // String generates the ssh config string
 func (s *SSHConfig) String() (string, error) {
-	fnMap := make(template.FuncMap)
-	fnMap["now"] = now
-	fnMap["version"] = util.VersionString
-
-	t, err := template.New("ssh_config").Funcs(fnMap).Parse(sshConfigTemplate)
+	t, err := parseSSHConfigTemplate()
 	if err != nil {
-		return "", errors.Wrap(err, "Could not parse ssh_config template")
+		return "", err
 	}
 
 	b := bytes.NewBuffer(nil)
@@ -67,6 +66,22 @@ func (s *SSHConfig) String() (string, error) {
 	return b.String(), nil
 }
 
+func parseSSHConfigTemplate() (*template.Template, error) {
+	fnMap := make(template.FuncMap)
+	fnMap["now"] = now
+	fnMap["version"] = util.VersionString
+
+	t, err := template.New("ssh_config").Funcs(fnMap).Parse(sshConfigTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not parse ssh_config template")
+	}
+	_, err = t.New("match").Parse(sshMatchTemplate)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not parse ssh_config template")
+	}
+	return t, nil
+}
+
 // Bastion is an internet accessibly server used to "jump" to other servers
 type Bastion struct {
 	Host `yaml:",inline"`
@@ -76,6 +91,21 @@ type Bastion struct {
 	SSHExecCommand *SSHExecCommand `yaml:"ssh_exec_command,omitempty"`
 }
 
+// MatchLine returns the Match line the generated ssh config has for b
+func (b *Bastion) MatchLine() (string, error) {
+	t, err := parseSSHConfigTemplate()
+	if err != nil {
+		return "", err
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err = t.ExecuteTemplate(buf, "match", b)
+	if err != nil {
+		return "", errors.Wrap(err, "Could not templetize ssh_config")
+	}
+	return buf.String(), nil
+}
+
 // SSHExecCommand is a command to execute on successful ssh match
 type SSHExecCommand string
 
@@ -189,3 +189,22 @@ Host 10.0.0.*
 	r.NoError(err)
 	r.Contains(config, expected)
 }
+
+func TestBastionMatchLine(t *testing.T) {
+	t.Parallel()
+	r := require.New(t)
+
+	sshConf := &config.SSHConfig{
+		Bastions: []config.Bastion{
+			{Host: config.Host{Pattern: "bastion.example.com", User: "admin"}},
+		},
+	}
+
+	match, err := sshConf.Bastions[0].MatchLine()
+	r.Nil(err)
+	r.Equal(`Match OriginalHost  bastion.example.com exec "blessclient run"`, match)
+
+	s, err := sshConf.String()
+	r.Nil(err)
+	r.Contains(s, match+"\n\tUser admin")
+}
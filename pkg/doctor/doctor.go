@@ -0,0 +1,263 @@
+// Package doctor checks the environment blessclient runs in for the problems
+// we see most often: no ssh agent, buggy ssh clients, broken configs, stuck
+// locks, missing ssh config and clocks that disagree with the CA.
+package doctor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	cziSSH "github.com/chanzuckerberg/blessclient/pkg/ssh"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Status is the outcome of a check
+type Status string
+
+// Statuses
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Result is the outcome of a single check
+type Result struct {
+	Name        string `json:"name"`
+	Status      Status `json:"status"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+func pass(name string, format string, args ...interface{}) *Result {
+	return &Result{Name: name, Status: StatusPass, Message: fmt.Sprintf(format, args...)}
+}
+
+func warn(name string, remediation string, format string, args ...interface{}) *Result {
+	return &Result{Name: name, Status: StatusWarn, Message: fmt.Sprintf(format, args...), Remediation: remediation}
+}
+
+func fail(name string, remediation string, format string, args ...interface{}) *Result {
+	return &Result{Name: name, Status: StatusFail, Message: fmt.Sprintf(format, args...), Remediation: remediation}
+}
+
+// Check names
+const (
+	CheckNameAgent      = "ssh-agent"
+	CheckNameSSHVersion = "ssh-version"
+	CheckNameConfig     = "config"
+	CheckNameLock       = "lock"
+	CheckNameSSHConfig  = "ssh-config"
+	CheckNameClock      = "clock"
+)
+
+// CheckAgent makes sure we can reach the ssh agent at authSock.
+// It returns the agent's certificates for CheckClock.
+func CheckAgent(authSock string) (*Result, []*ssh.Certificate) {
+	if authSock == "" {
+		return fail(CheckNameAgent,
+			"Start an ssh agent with `eval $(ssh-agent)` or enable it in your ssh client.",
+			"SSH_AUTH_SOCK is not set"), nil
+	}
+
+	a, err := cziSSH.GetSSHAgent(authSock)
+	if err != nil {
+		return fail(CheckNameAgent,
+			"SSH_AUTH_SOCK points at an agent that is gone, start a new shell or a new agent.",
+			"could not reach the ssh agent: %s", err), nil
+	}
+	defer a.Close()
+
+	keys, err := a.List()
+	if err != nil {
+		return fail(CheckNameAgent,
+			"Restart your ssh agent.",
+			"could not list agent keys: %s", err), nil
+	}
+	return pass(CheckNameAgent, "reachable at %s with %d keys", authSock, len(keys)), agentCertificates(keys)
+}
+
+// agentCertificates returns the certificates minted by the CA in keys
+func agentCertificates(keys []*agent.Key) []*ssh.Certificate {
+	certs := []*ssh.Certificate{}
+	for _, key := range keys {
+		pub, err := ssh.ParsePublicKey(key.Marshal())
+		if err != nil {
+			continue
+		}
+		cert, ok := pub.(*ssh.Certificate)
+		if !ok || !cziSSH.IsBlessCertificate(cert) {
+			continue
+		}
+		certs = append(certs, cert)
+	}
+	return certs
+}
+
+var sshVersionPattern = regexp.MustCompile(`OpenSSH_(\d+)\.(\d+)`)
+
+// CheckSSHVersion parses the output of `ssh -V` and flags clients with known certificate bugs
+func CheckSSHVersion(output string) *Result {
+	output = strings.TrimSpace(output)
+	match := sshVersionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return warn(CheckNameSSHVersion, "", "could not parse ssh version from %q", output)
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+
+	if major == 7 && minor == 8 {
+		return fail(CheckNameSSHVersion,
+			"OpenSSH 7.8 can't connect with certificates, upgrade your ssh client. "+
+				"See \"SSH client 7.8 can't connect with certificates\" in the blessclient README.",
+			"%s has known certificate bugs", output)
+	}
+	return pass(CheckNameSSHVersion, "%s", output)
+}
+
+// CheckConfig validates the blessclient config at configPath
+func CheckConfig(configPath string) (*Result, *config.Config) {
+	remediation := fmt.Sprintf("Fix %s or import a fresh one with `blessclient import-config`.", configPath)
+
+	conf, err := config.FromFile(configPath)
+	if err != nil {
+		return fail(CheckNameConfig, remediation, "%s", err), nil
+	}
+	_, err = conf.ClientConfig.GetIdentity()
+	if err != nil {
+		return fail(CheckNameConfig, remediation, "%s", err), conf
+	}
+	err = conf.CheckClientVersion()
+	if err != nil {
+		return fail(CheckNameConfig, "Install a newer blessclient, see https://github.com/chanzuckerberg/blessclient#install.", "%s", err), conf
+	}
+
+	missing := []string{}
+	if conf.ClientConfig.OIDCIssuerURL == "" {
+		missing = append(missing, "client_config.oidc_issuer_url")
+	}
+	if conf.ClientConfig.OIDCClientID == "" {
+		missing = append(missing, "client_config.oidc_client_id")
+	}
+	if conf.ClientConfig.RoleARN == "" {
+		missing = append(missing, "client_config.role_arn")
+	}
+	if conf.LambdaConfig.FunctionName == "" {
+		missing = append(missing, "lambda_config.function_name")
+	}
+	if len(conf.LambdaConfig.Regions) == 0 {
+		missing = append(missing, "lambda_config.regions")
+	}
+	if len(missing) > 0 {
+		return fail(CheckNameConfig, remediation, "missing %s", strings.Join(missing, ", ")), conf
+	}
+	return pass(CheckNameConfig, "%s is valid", configPath), conf
+}
+
+// CheckLock reports who holds the pid lock
+func CheckLock(lock *util.Lock) *Result {
+	holder, err := lock.Holder()
+	switch {
+	case err != nil:
+		return warn(CheckNameLock, "", "could not read lock at %s: %s", lock.Path(), err)
+	case holder == nil:
+		return pass(CheckNameLock, "not held")
+	case holder.Stale:
+		return warn(CheckNameLock,
+			"The next blessclient run removes it, or remove it yourself.",
+			"stale lock at %s held by %s", lock.Path(), holder)
+	case holder.PID == os.Getpid():
+		return pass(CheckNameLock, "held by this blessclient")
+	default:
+		return warn(CheckNameLock,
+			"Another blessclient is running, probably waiting for you to log in. Finish or cancel that login.",
+			"held by %s", holder)
+	}
+}
+
+// CheckSSHConfig makes sure the ssh config at sshConfigPath
+// runs blessclient for the bastions in conf
+func CheckSSHConfig(sshConfigPath string, conf *config.Config) *Result {
+	remediation := "Run `blessclient import-config` to generate it or see \".ssh/config\" in the blessclient README."
+
+	data, err := ioutil.ReadFile(sshConfigPath)
+	if os.IsNotExist(err) {
+		return fail(CheckNameSSHConfig, remediation, "%s does not exist", sshConfigPath)
+	}
+	if err != nil {
+		return warn(CheckNameSSHConfig, "", "could not read %s: %s", sshConfigPath, err)
+	}
+	sshConfig := string(data)
+
+	if conf == nil || conf.SSHConfig == nil {
+		if strings.Contains(sshConfig, "blessclient") {
+			return pass(CheckNameSSHConfig, "%s runs blessclient", sshConfigPath)
+		}
+		return warn(CheckNameSSHConfig, remediation,
+			"%s never runs blessclient, you have to run `blessclient run` before ssh", sshConfigPath)
+	}
+
+	missing := []string{}
+	for _, bastion := range conf.SSHConfig.Bastions {
+		match, err := bastion.MatchLine()
+		if err != nil {
+			return warn(CheckNameSSHConfig, "", "could not render the Match block for %s: %s", bastion.Pattern, err)
+		}
+		if !strings.Contains(sshConfig, match) {
+			missing = append(missing, bastion.Pattern)
+		}
+	}
+	if len(missing) > 0 {
+		return fail(CheckNameSSHConfig, remediation,
+			"%s has no Match block for %s", sshConfigPath, strings.Join(missing, ", "))
+	}
+	return pass(CheckNameSSHConfig, "%s has the generated Match blocks", sshConfigPath)
+}
+
+// CheckClock compares now with the validity of the certificates minted by the CA
+func CheckClock(certs []*ssh.Certificate, now time.Time) *Result {
+	if len(certs) == 0 {
+		return pass(CheckNameClock, "no certificates to compare the clock with")
+	}
+
+	var latest *ssh.Certificate
+	for _, cert := range certs {
+		validAfter := time.Unix(int64(cert.ValidAfter), 0)
+		if now.Before(validAfter) {
+			return fail(CheckNameClock,
+				"Your clock is behind the CA's. Enable time synchronization (NTP) and try again.",
+				"certificate %s is only valid from %s, %s from now",
+				cert.KeyId, validAfter.UTC().Format(time.RFC3339), validAfter.Sub(now).Round(time.Second))
+		}
+		if latest == nil || cert.ValidBefore > latest.ValidBefore {
+			latest = cert
+		}
+	}
+
+	validBefore := time.Unix(int64(latest.ValidBefore), 0)
+	if !now.Before(validBefore) {
+		return warn(CheckNameClock,
+			"Run `blessclient run` to get a new certificate.",
+			"certificate %s expired at %s", latest.KeyId, validBefore.UTC().Format(time.RFC3339))
+	}
+	return pass(CheckNameClock, "certificate %s is valid for another %s", latest.KeyId, validBefore.Sub(now).Round(time.Second))
+}
+
+// Failed returns how many results failed
+func Failed(results []*Result) int {
+	failed := 0
+	for _, result := range results {
+		if result.Status == StatusFail {
+			failed++
+		}
+	}
+	return failed
+}
@@ -0,0 +1,172 @@
+package doctor
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/chanzuckerberg/blessclient/pkg/config"
+	"github.com/chanzuckerberg/blessclient/pkg/ssh/sshtest"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestCheckAgent(t *testing.T) {
+	r := require.New(t)
+
+	result, certs := CheckAgent("")
+	r.Equal(StatusFail, result.Status)
+	r.Nil(certs)
+
+	result, _ = CheckAgent(path.Join(t.TempDir(), "nope.sock"))
+	r.Equal(StatusFail, result.Status)
+
+	// serve a keyring with one of our certificates
+	keyring := agent.NewKeyring()
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	r.NoError(err)
+	cert, priv := sshtest.NewKeyAndCert(r, time.Now().Add(-time.Minute), time.Now().Add(time.Hour))
+	r.NoError(keyring.Add(agent.AddedKey{PrivateKey: otherPriv}))
+	r.NoError(keyring.Add(agent.AddedKey{PrivateKey: priv, Certificate: cert}))
+
+	authSock := path.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", authSock)
+	r.NoError(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(keyring, conn) // nolint: errcheck
+		}
+	}()
+
+	result, certs = CheckAgent(authSock)
+	r.Equal(StatusPass, result.Status, result.Message)
+	r.Contains(result.Message, "2 keys")
+	r.Len(certs, 1)
+	r.Equal(sshtest.KeyID, certs[0].KeyId)
+}
+
+func TestCheckSSHVersion(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(StatusPass, CheckSSHVersion("OpenSSH_9.2p1 Debian-2+deb12u3, OpenSSL 3.0.13 30 Jan 2024\n").Status)
+	r.Equal(StatusPass, CheckSSHVersion("OpenSSH_7.9p1, LibreSSL 2.7.3").Status)
+	r.Equal(StatusWarn, CheckSSHVersion("dropbear v2022.83").Status)
+
+	result := CheckSSHVersion("OpenSSH_7.8p1, OpenSSL 1.1.0h  27 Mar 2018")
+	r.Equal(StatusFail, result.Status)
+	r.Contains(result.Remediation, "upgrade your ssh client")
+}
+
+func TestCheckConfig(t *testing.T) {
+	r := require.New(t)
+	configPath := path.Join(t.TempDir(), "config.yml")
+
+	result, conf := CheckConfig(configPath)
+	r.Equal(StatusFail, result.Status)
+	r.Nil(conf)
+
+	conf = &config.Config{Version: config.ConfigVersion}
+	r.NoError(conf.Persist(configPath))
+	result, _ = CheckConfig(configPath)
+	r.Equal(StatusFail, result.Status)
+	r.Contains(result.Message, "client_config.oidc_issuer_url")
+	r.Contains(result.Message, "lambda_config.regions")
+
+	conf.ClientConfig.OIDCIssuerURL = "https://issuer.example.com"
+	conf.ClientConfig.OIDCClientID = "client"
+	conf.ClientConfig.RoleARN = "arn:aws:iam::123456789012:role/bless"
+	conf.LambdaConfig.FunctionName = "bless"
+	conf.LambdaConfig.Regions = []config.Region{{AWSRegion: "us-west-2"}}
+	r.NoError(conf.Persist(configPath))
+	result, _ = CheckConfig(configPath)
+	r.Equal(StatusPass, result.Status, result.Message)
+
+	conf.Version = config.ConfigVersion + 1
+	r.NoError(conf.Persist(configPath))
+	result, _ = CheckConfig(configPath)
+	r.Equal(StatusFail, result.Status)
+	r.Contains(result.Message, "expected config version")
+}
+
+func TestCheckLock(t *testing.T) {
+	r := require.New(t)
+	configPath := path.Join(t.TempDir(), "config.yml")
+
+	lock, err := util.NewLock(configPath)
+	r.NoError(err)
+	r.Equal(StatusPass, CheckLock(lock).Status)
+
+	r.NoError(lock.Lock())
+	result := CheckLock(lock)
+	r.Equal(StatusPass, result.Status)
+	r.Equal("held by this blessclient", result.Message)
+	r.NoError(lock.Unlock())
+
+	// a pid that can't be running
+	r.NoError(ioutil.WriteFile(lock.Path(), []byte("999999999\n"), 0644))
+	result = CheckLock(lock)
+	r.Equal(StatusWarn, result.Status)
+	r.Contains(result.Message, "stale lock")
+}
+
+func TestCheckSSHConfig(t *testing.T) {
+	r := require.New(t)
+	sshConfigPath := path.Join(t.TempDir(), "config")
+
+	r.Equal(StatusFail, CheckSSHConfig(sshConfigPath, nil).Status)
+
+	r.NoError(ioutil.WriteFile(sshConfigPath, []byte("Host *\n\tUser me\n"), 0600))
+	r.Equal(StatusWarn, CheckSSHConfig(sshConfigPath, nil).Status)
+
+	conf := &config.Config{SSHConfig: &config.SSHConfig{Bastions: []config.Bastion{
+		{Host: config.Host{Pattern: "bastion.example.com", User: "admin"}},
+	}}}
+	result := CheckSSHConfig(sshConfigPath, conf)
+	r.Equal(StatusFail, result.Status)
+	r.Contains(result.Message, "bastion.example.com")
+
+	generated, err := conf.SSHConfig.String()
+	r.NoError(err)
+	r.NoError(ioutil.WriteFile(sshConfigPath, []byte(generated), 0600))
+	r.Equal(StatusPass, CheckSSHConfig(sshConfigPath, conf).Status)
+}
+
+func TestCheckClock(t *testing.T) {
+	r := require.New(t)
+	now := time.Now()
+
+	r.Equal(StatusPass, CheckClock(nil, now).Status)
+
+	valid, _ := sshtest.NewKeyAndCert(r, now.Add(-time.Minute), now.Add(time.Hour))
+	r.Equal(StatusPass, CheckClock([]*ssh.Certificate{valid}, now).Status)
+
+	expired, _ := sshtest.NewKeyAndCert(r, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	r.Equal(StatusWarn, CheckClock([]*ssh.Certificate{expired}, now).Status)
+	r.Equal(StatusPass, CheckClock([]*ssh.Certificate{expired, valid}, now).Status)
+
+	// our clock is behind the CA's
+	future, _ := sshtest.NewKeyAndCert(r, now.Add(10*time.Minute), now.Add(time.Hour))
+	result := CheckClock([]*ssh.Certificate{future}, now)
+	r.Equal(StatusFail, result.Status)
+	r.Contains(result.Remediation, "NTP")
+}
+
+func TestFailed(t *testing.T) {
+	r := require.New(t)
+	r.Equal(1, Failed([]*Result{
+		pass("a", "ok"),
+		warn("b", "", "hmm"),
+		fail("c", "", "no"),
+	}))
+}
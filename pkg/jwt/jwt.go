@@ -0,0 +1,203 @@
+// Package jwt decodes and verifies json web tokens for debugging
+package jwt
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// signature algorithms we accept when verifying
+var supportedAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// claims holding unix timestamps we print in a human readable way
+var timeClaims = []string{"exp", "iat", "nbf", "auth_time"}
+
+// Token is a decoded, but not necessarily verified, json web token
+type Token struct {
+	Header map[string]interface{}
+	Claims map[string]interface{}
+
+	raw string
+}
+
+// Decode decodes raw without verifying it
+func Decode(raw string) (*Token, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.Errorf("expected 3 parts in a jwt, found %d", len(parts))
+	}
+
+	t := &Token{raw: raw}
+	err := decodePart(parts[0], &t.Header)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode jwt header")
+	}
+	err = decodePart(parts[1], &t.Claims)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode jwt claims")
+	}
+	return t, nil
+}
+
+func decodePart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(part, "="))
+	if err != nil {
+		return errors.Wrap(err, "could not b64 decode")
+	}
+
+	d := json.NewDecoder(strings.NewReader(string(b)))
+	// keep timestamps as integers
+	d.UseNumber()
+	return errors.Wrap(d.Decode(v), "could not json decode")
+}
+
+// Expiry returns the exp claim, or the zero time if there is none
+func (t *Token) Expiry() time.Time {
+	exp, ok := t.time("exp")
+	if !ok {
+		return time.Time{}
+	}
+	return exp
+}
+
+func (t *Token) time(claim string) (time.Time, bool) {
+	n, ok := t.Claims[claim].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	secs, err := n.Int64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(secs, 0), true
+}
+
+// Verify verifies the token signature against keys
+func (t *Token) Verify(keys *jose.JSONWebKeySet) error {
+	jws, err := jose.ParseSigned(t.raw, supportedAlgorithms)
+	if err != nil {
+		return errors.Wrap(err, "could not parse jws")
+	}
+	if len(jws.Signatures) != 1 {
+		return errors.Errorf("expected 1 signature, found %d", len(jws.Signatures))
+	}
+
+	candidates := keys.Keys
+	kid := jws.Signatures[0].Header.KeyID
+	if kid != "" {
+		candidates = keys.Key(kid)
+		if len(candidates) == 0 {
+			return errors.Errorf("no key with kid %s in jwks", kid)
+		}
+	}
+
+	for _, key := range candidates {
+		key := key
+		_, err = jws.Verify(&key)
+		if err == nil {
+			return nil
+		}
+	}
+	return errors.New("signature does not match any key in jwks")
+}
+
+// Print writes a human readable representation of the token to w
+func (t *Token) Print(w io.Writer, now time.Time) error {
+	claims := map[string]interface{}{}
+	for k, v := range t.Claims {
+		claims[k] = v
+	}
+	for _, claim := range timeClaims {
+		ts, ok := t.time(claim)
+		if !ok {
+			continue
+		}
+		claims[claim] = fmt.Sprintf("%s (%s)", ts.UTC().Format(time.RFC3339), relative(ts, now))
+	}
+
+	data, err := yaml.Marshal(map[string]interface{}{
+		"header": t.Header,
+		"claims": claims,
+	})
+	if err != nil {
+		return errors.Wrap(err, "could not yaml marshal token")
+	}
+	_, err = fmt.Fprint(w, string(data))
+	return errors.Wrap(err, "could not print token")
+}
+
+func relative(ts time.Time, now time.Time) string {
+	d := ts.Sub(now).Round(time.Second)
+	if d >= 0 {
+		return fmt.Sprintf("in %s", d)
+	}
+	return fmt.Sprintf("%s ago", -d)
+}
+
+// ReadJWKS reads a json web key set from a file
+func ReadJWKS(jwksPath string) (*jose.JSONWebKeySet, error) {
+	b, err := ioutil.ReadFile(jwksPath) // #nosec
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read jwks from %s", jwksPath)
+	}
+	keys := &jose.JSONWebKeySet{}
+	err = json.Unmarshal(b, keys)
+	return keys, errors.Wrapf(err, "could not json unmarshal jwks from %s", jwksPath)
+}
+
+type discovery struct {
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// FetchJWKS fetches the json web key set of an oidc issuer through its discovery document
+func FetchJWKS(ctx context.Context, httpClient *http.Client, issuerURL string) (*jose.JSONWebKeySet, error) {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	d := &discovery{}
+	discoveryURL := strings.TrimSuffix(issuerURL, "/") + "/.well-known/openid-configuration"
+	err := getJSON(ctx, httpClient, discoveryURL, d)
+	if err != nil {
+		return nil, err
+	}
+	if d.JWKSURI == "" {
+		return nil, errors.Errorf("no jwks_uri in %s", discoveryURL)
+	}
+
+	keys := &jose.JSONWebKeySet{}
+	return keys, getJSON(ctx, httpClient, d.JWKSURI, keys)
+}
+
+func getJSON(ctx context.Context, httpClient *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrapf(err, "could not create request for %s", url)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "could not fetch %s", url)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("fetching %s returned %s", url, resp.Status)
+	}
+	return errors.Wrapf(json.NewDecoder(resp.Body).Decode(v), "could not json decode %s", url)
+}
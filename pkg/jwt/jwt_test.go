@@ -0,0 +1,127 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestToken(r *require.Assertions, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: "test"}},
+		(&jose.SignerOptions{}).WithType("JWT"))
+	r.NoError(err)
+
+	payload, err := json.Marshal(claims)
+	r.NoError(err)
+	jws, err := signer.Sign(payload)
+	r.NoError(err)
+	raw, err := jws.CompactSerialize()
+	r.NoError(err)
+	return raw
+}
+
+func newTestJWKS(key *rsa.PrivateKey) *jose.JSONWebKeySet {
+	return &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+		Key:       key.Public(),
+		KeyID:     "test",
+		Algorithm: string(jose.RS256),
+		Use:       "sig",
+	}}}
+}
+
+func TestDecodeAndPrint(t *testing.T) {
+	r := require.New(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	r.NoError(err)
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	raw := newTestToken(r, key, map[string]interface{}{
+		"iss":   "https://issuer.example.com",
+		"email": "user@example.com",
+		"iat":   now.Add(-time.Hour).Unix(),
+		"exp":   now.Add(45 * time.Minute).Unix(),
+	})
+
+	token, err := Decode(raw)
+	r.NoError(err)
+	r.Equal("RS256", token.Header["alg"])
+	r.Equal("test", token.Header["kid"])
+	r.Equal(now.Add(45*time.Minute), token.Expiry().UTC())
+
+	buf := bytes.NewBuffer(nil)
+	r.NoError(token.Print(buf, now))
+	r.Contains(buf.String(), "email: user@example.com")
+	r.Contains(buf.String(), "2020-01-01T12:45:00Z (in 45m0s)")
+	r.Contains(buf.String(), "2020-01-01T11:00:00Z (1h0m0s ago)")
+}
+
+func TestDecodeOpaque(t *testing.T) {
+	r := require.New(t)
+	_, err := Decode("not-a-jwt")
+	r.Error(err)
+}
+
+func TestVerifyWithFile(t *testing.T) {
+	r := require.New(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	r.NoError(err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	r.NoError(err)
+
+	dir, err := ioutil.TempDir("", "blessclient-jwt")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+	jwksPath := path.Join(dir, "jwks.json")
+	data, err := json.Marshal(newTestJWKS(key))
+	r.NoError(err)
+	r.NoError(ioutil.WriteFile(jwksPath, data, 0600))
+
+	keys, err := ReadJWKS(jwksPath)
+	r.NoError(err)
+
+	token, err := Decode(newTestToken(r, key, map[string]interface{}{"sub": "user"}))
+	r.NoError(err)
+	r.NoError(token.Verify(keys))
+
+	// signed by someone else with the same kid
+	forged, err := Decode(newTestToken(r, other, map[string]interface{}{"sub": "user"}))
+	r.NoError(err)
+	r.Error(forged.Verify(keys))
+}
+
+func TestFetchJWKS(t *testing.T) {
+	r := require.New(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	r.NoError(err)
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, req *http.Request) {
+		r.NoError(json.NewEncoder(w).Encode(map[string]string{"jwks_uri": server.URL + "/keys"}))
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, req *http.Request) {
+		r.NoError(json.NewEncoder(w).Encode(newTestJWKS(key)))
+	})
+	server = httptest.NewServer(mux)
+	defer server.Close()
+
+	keys, err := FetchJWKS(context.Background(), nil, server.URL)
+	r.NoError(err)
+
+	token, err := Decode(newTestToken(r, key, map[string]interface{}{"sub": "user"}))
+	r.NoError(err)
+	r.NoError(token.Verify(keys))
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// keep a few small files, enough to cover the last few days of ssh attempts
+	maxFileSize    = 10 * 1024 * 1024
+	maxFileBackups = 5
+	maxFileAge     = 30 * 24 * time.Hour
+
+	backupTimeFormat = "2006-01-02T15-04-05.000"
+)
+
+// openLogFile opens filePath for appending.
+// Concurrent blessclients share the file, each entry is a single append so they don't
+// overwrite each other. Nobody rotates the file while it is open, whoever opens it once
+// it is too big moves it aside instead, the others keep appending to the old one until they exit.
+func openLogFile(filePath string, now time.Time) (*os.File, error) {
+	expanded, err := homedir.Expand(filePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not expand %s", filePath)
+	}
+	err = os.MkdirAll(filepath.Dir(expanded), 0700)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create %s", filepath.Dir(expanded))
+	}
+
+	info, err := os.Stat(expanded)
+	if err == nil && info.Size() >= maxFileSize {
+		err = rotateLogFile(expanded, now)
+		if err != nil {
+			// we'd rather have a big log file than none
+			logrus.WithError(err).Debug("could not rotate the log file")
+		}
+	}
+
+	f, err := os.OpenFile(expanded, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600) // #nosec
+	return f, errors.Wrapf(err, "could not open %s", expanded)
+}
+
+// rotateLogFile moves filePath aside and removes backups we no longer keep
+func rotateLogFile(filePath string, now time.Time) error {
+	ext := filepath.Ext(filePath)
+	prefix := strings.TrimSuffix(filePath, ext) + "-"
+	backup := fmt.Sprintf("%s%s%s", prefix, now.UTC().Format(backupTimeFormat), ext)
+
+	err := os.Rename(filePath, backup)
+	// someone else rotated it first
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "could not move %s to %s", filePath, backup)
+	}
+
+	backups, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return errors.Wrap(err, "could not list log backups")
+	}
+	// the timestamps sort oldest first
+	sort.Strings(backups)
+	for i, b := range backups {
+		info, err := os.Stat(b)
+		if err != nil {
+			continue
+		}
+		if i < len(backups)-maxFileBackups || now.Sub(info.ModTime()) > maxFileAge {
+			err = os.Remove(b)
+			if err != nil && !os.IsNotExist(err) {
+				return errors.Wrapf(err, "could not remove %s", b)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenLogFileAppends(t *testing.T) {
+	r := require.New(t)
+	logFile := filepath.Join(t.TempDir(), "logs", "blessclient.log")
+	now := time.Now()
+
+	// two blessclients writing at once
+	first, err := openLogFile(logFile, now)
+	r.NoError(err)
+	defer first.Close()
+	second, err := openLogFile(logFile, now)
+	r.NoError(err)
+	defer second.Close()
+
+	for i := 0; i < 10; i++ {
+		_, err = first.Write([]byte("first\n"))
+		r.NoError(err)
+		_, err = second.Write([]byte("second\n"))
+		r.NoError(err)
+	}
+
+	data, err := ioutil.ReadFile(logFile)
+	r.NoError(err)
+	r.Equal(10, strings.Count(string(data), "first\n"))
+	r.Equal(10, strings.Count(string(data), "second\n"))
+}
+
+func TestOpenLogFileRotates(t *testing.T) {
+	r := require.New(t)
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "blessclient.log")
+	now := time.Now()
+
+	r.NoError(ioutil.WriteFile(logFile, bytes.Repeat([]byte("a"), maxFileSize), 0600))
+	// one backup too many, and one too old
+	for i := 0; i < maxFileBackups; i++ {
+		backup := filepath.Join(dir, "blessclient-"+now.Add(time.Duration(i-maxFileBackups)*time.Minute).UTC().Format(backupTimeFormat)+".log")
+		r.NoError(ioutil.WriteFile(backup, []byte("old"), 0600))
+	}
+	stale := filepath.Join(dir, "blessclient-2000-01-01T00-00-00.000.log")
+	r.NoError(ioutil.WriteFile(stale, []byte("stale"), 0600))
+	r.NoError(os.Chtimes(stale, now.Add(-maxFileAge-time.Hour), now.Add(-maxFileAge-time.Hour)))
+
+	f, err := openLogFile(logFile, now)
+	r.NoError(err)
+	defer f.Close()
+
+	info, err := os.Stat(logFile)
+	r.NoError(err)
+	r.Zero(info.Size())
+
+	backups, err := filepath.Glob(filepath.Join(dir, "blessclient-*.log"))
+	r.NoError(err)
+	r.Len(backups, maxFileBackups)
+	r.NotContains(backups, stale)
+	r.Contains(backups, filepath.Join(dir, "blessclient-"+now.UTC().Format(backupTimeFormat)+".log"))
+}
+
+func TestOpenLogFileSmall(t *testing.T) {
+	r := require.New(t)
+	logFile := filepath.Join(t.TempDir(), "blessclient.log")
+	r.NoError(ioutil.WriteFile(logFile, []byte("keep\n"), 0600))
+
+	f, err := openLogFile(logFile, time.Now())
+	r.NoError(err)
+	defer f.Close()
+
+	data, err := ioutil.ReadFile(logFile)
+	r.NoError(err)
+	r.Equal("keep\n", string(data))
+}
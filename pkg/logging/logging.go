@@ -0,0 +1,123 @@
+// Package logging configures logrus for the cli: the format and level of what we
+// print to stderr and a debug log file that support can ask for.
+package logging
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// FormatText is logrus' human readable format
+	FormatText = "text"
+	// FormatJSON logs one json object per line
+	FormatJSON = "json"
+)
+
+// Config configures logging
+type Config struct {
+	// Format of the logs printed to stderr, text or json
+	Format string
+	// Level of the logs printed to stderr
+	Level string
+	// File, if set, receives debug logs in json regardless of Format and Level
+	File string
+}
+
+// Setup configures logger according to conf. Every message goes through redaction.
+func Setup(logger *logrus.Logger, conf *Config) error {
+	formatter, err := newFormatter(conf.Format)
+	if err != nil {
+		return err
+	}
+	level, err := logrus.ParseLevel(conf.Level)
+	if err != nil {
+		return errors.Wrapf(err, "unknown log level %s", conf.Level)
+	}
+
+	// stderr stays the logger's output so the text formatter still sees the terminal
+	logger.SetOutput(os.Stderr)
+	logger.SetLevel(level)
+	logger.SetFormatter(&levelFormatter{
+		formatter: &redactingFormatter{formatter: formatter},
+		level:     level,
+	})
+	logger.ReplaceHooks(logrus.LevelHooks{})
+
+	if conf.File == "" {
+		return nil
+	}
+	file, err := openLogFile(conf.File, time.Now())
+	if err != nil {
+		// the log file is a nice to have, don't fail the command over it
+		logger.WithError(err).Warn("could not open the log file, only logging to stderr")
+		return nil
+	}
+	logger.SetLevel(logrus.DebugLevel)
+	logger.AddHook(&writerHook{
+		writer:    file,
+		formatter: &redactingFormatter{formatter: &logrus.JSONFormatter{}},
+		levels:    levelsUpTo(logrus.DebugLevel),
+	})
+	return nil
+}
+
+func newFormatter(format string) (logrus.Formatter, error) {
+	switch format {
+	case FormatText, "":
+		return &logrus.TextFormatter{}, nil
+	case FormatJSON:
+		return &logrus.JSONFormatter{}, nil
+	default:
+		return nil, errors.Errorf("unknown log format %s, expected text or json", format)
+	}
+}
+
+// levelsUpTo returns every level at least as severe as level
+func levelsUpTo(level logrus.Level) []logrus.Level {
+	levels := []logrus.Level{}
+	for _, l := range logrus.AllLevels {
+		if l <= level {
+			levels = append(levels, l)
+		}
+	}
+	return levels
+}
+
+// levelFormatter drops entries less severe than level, so the logger can run at
+// debug for the file while stderr only gets what was asked for
+type levelFormatter struct {
+	formatter logrus.Formatter
+	level     logrus.Level
+}
+
+func (f *levelFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Level > f.level {
+		return nil, nil
+	}
+	return f.formatter.Format(entry)
+}
+
+// writerHook writes the entries at levels to writer
+type writerHook struct {
+	writer    io.Writer
+	formatter logrus.Formatter
+	levels    []logrus.Level
+}
+
+func (h *writerHook) Levels() []logrus.Level {
+	return h.levels
+}
+
+func (h *writerHook) Fire(entry *logrus.Entry) error {
+	b, err := h.formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = h.writer.Write(b)
+	return err
+}
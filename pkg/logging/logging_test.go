@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupFile(t *testing.T) {
+	r := require.New(t)
+	logFile := path.Join(t.TempDir(), "logs", "blessclient.log")
+
+	logger := logrus.New()
+	err := Setup(logger, &Config{Format: FormatText, Level: "warn", File: logFile})
+	r.NoError(err)
+
+	logger.WithField("region", "us-west-2").Debug("attempting to get cert")
+	logger.Info("got cert")
+
+	data, err := ioutil.ReadFile(logFile)
+	r.NoError(err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	r.Len(lines, 2)
+
+	// the file gets debug logs as json even if stderr only gets warnings
+	entry := map[string]interface{}{}
+	r.NoError(json.Unmarshal([]byte(lines[0]), &entry))
+	r.Equal("debug", entry["level"])
+	r.Equal("attempting to get cert", entry["msg"])
+	r.Equal("us-west-2", entry["region"])
+}
+
+func TestSetupNoFile(t *testing.T) {
+	r := require.New(t)
+
+	logger := logrus.New()
+	err := Setup(logger, &Config{Format: FormatJSON, Level: "info"})
+	r.NoError(err)
+	r.Equal(logrus.InfoLevel, logger.GetLevel())
+	r.False(logger.IsLevelEnabled(logrus.DebugLevel))
+}
+
+func TestSetupInvalid(t *testing.T) {
+	r := require.New(t)
+
+	err := Setup(logrus.New(), &Config{Format: "xml", Level: "info"})
+	r.Error(err)
+	r.Contains(err.Error(), "unknown log format xml")
+
+	err = Setup(logrus.New(), &Config{Format: FormatText, Level: "loud"})
+	r.Error(err)
+	r.Contains(err.Error(), "unknown log level loud")
+}
+
+func TestSetupStderr(t *testing.T) {
+	r := require.New(t)
+	logFile := path.Join(t.TempDir(), "blessclient.log")
+
+	logger := logrus.New()
+	r.NoError(Setup(logger, &Config{Format: FormatText, Level: "info", File: logFile}))
+	// the text formatter checks the output for a terminal
+	r.Equal(os.Stderr, logger.Out)
+
+	logs := bytes.NewBuffer(nil)
+	logger.SetOutput(logs)
+	logger.Debug("attempting to get cert")
+	logger.Info("got cert")
+	r.NotContains(logs.String(), "attempting to get cert")
+	r.Contains(logs.String(), "got cert")
+
+	data, err := ioutil.ReadFile(logFile)
+	r.NoError(err)
+	r.Contains(string(data), "attempting to get cert")
+}
+
+func TestSetupFileUnavailable(t *testing.T) {
+	r := require.New(t)
+	dir := t.TempDir()
+	// logs is a file, so the log file can't go in it
+	r.NoError(ioutil.WriteFile(path.Join(dir, "logs"), []byte{}, 0600))
+
+	logger := logrus.New()
+	r.NoError(Setup(logger, &Config{Format: FormatText, Level: "info", File: path.Join(dir, "logs", "blessclient.log")}))
+	r.Equal(logrus.InfoLevel, logger.GetLevel())
+	r.Equal(os.Stderr, logger.Out)
+}
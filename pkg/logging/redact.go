@@ -0,0 +1,20 @@
+package logging
+
+import (
+	"github.com/chanzuckerberg/blessclient/pkg/redact"
+	"github.com/sirupsen/logrus"
+)
+
+// redactingFormatter removes secrets from formatted entries,
+// catching anything the code logging it didn't redact itself
+type redactingFormatter struct {
+	formatter logrus.Formatter
+}
+
+func (f *redactingFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	b, err := f.formatter.Format(entry)
+	if err != nil {
+		return nil, err
+	}
+	return redact.Bytes(b), nil
+}
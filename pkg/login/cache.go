@@ -0,0 +1,56 @@
+package login
+
+import (
+	"context"
+
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/cache"
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/storage"
+	"github.com/chanzuckerberg/go-misc/pidlock"
+	"github.com/pkg/errors"
+)
+
+type refreshFunc func(context.Context, *client.Token) (*client.Token, error)
+
+// errStaleToken stops the oidc_cli cache from refreshing a token we only want to read
+var errStaleToken = errors.New("stale token")
+
+// forceRefresh refreshes token even though it is still fresh.
+// It goes through the oidc_cli cache so the result is stored the way oidc_cli reads it.
+func forceRefresh(
+	ctx context.Context,
+	tokenStorage storage.Storage,
+	lock *pidlock.Lock,
+	refresh refreshFunc,
+	token *client.Token,
+) (*client.Token, error) {
+	refreshToken := func(ctx context.Context, _ *client.Token) (*client.Token, error) {
+		return refresh(ctx, token)
+	}
+	return cache.NewCache(&missingStorage{Storage: tokenStorage}, refreshToken, lock).Read(ctx)
+}
+
+// readCachedToken reads the token oidc_cli cached in tokenStorage, if any, even an expired one
+func readCachedToken(ctx context.Context, tokenStorage storage.Storage, lock *pidlock.Lock) (*client.Token, error) {
+	// the cache hands tokens that aren't fresh to refresh, keep those as they are
+	var stale *client.Token
+	keep := func(ctx context.Context, token *client.Token) (*client.Token, error) {
+		stale = token
+		return nil, errStaleToken
+	}
+	token, err := cache.NewCache(tokenStorage, keep, lock).Read(ctx)
+	if errors.Is(err, errStaleToken) {
+		return stale, nil
+	}
+	return token, errors.Wrap(err, "could not read cached token")
+}
+
+// missingStorage never has a token, so the oidc_cli cache always refreshes
+// and stores the result in the storage it wraps
+type missingStorage struct {
+	storage.Storage
+}
+
+func (s *missingStorage) Read(ctx context.Context) (*string, error) {
+	return nil, nil
+}
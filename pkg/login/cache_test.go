@@ -0,0 +1,81 @@
+package login
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/cache"
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/storage"
+	"github.com/chanzuckerberg/go-misc/pidlock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForceRefresh(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "blessclient-login-cache")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	tokenStorage := storage.NewFile(dir, "client-id", "https://issuer")
+	lock, err := pidlock.NewLock(path.Join(dir, "lock"))
+	r.NoError(err)
+
+	oldToken := &client.Token{IDToken: "old", Expiry: time.Now().Add(10 * time.Minute)}
+	refresh := func(ctx context.Context, token *client.Token) (*client.Token, error) {
+		r.Equal("old", token.IDToken)
+		return &client.Token{IDToken: "new", Expiry: time.Now().Add(time.Hour)}, nil
+	}
+
+	token, err := forceRefresh(ctx, tokenStorage, lock, refresh, oldToken)
+	r.NoError(err)
+	r.Equal("new", token.IDToken)
+
+	// the oidc_cli cache can read what we stored without refreshing
+	noRefresh := func(ctx context.Context, token *client.Token) (*client.Token, error) {
+		return nil, errors.New("should not refresh")
+	}
+	cached, err := cache.NewCache(tokenStorage, noRefresh, lock).Read(ctx)
+	r.NoError(err)
+	r.Equal("new", cached.IDToken)
+}
+
+func TestReadCachedToken(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	tokenStorage := storage.NewFile(dir, "client-id", "https://issuer")
+	lock, err := pidlock.NewLock(path.Join(dir, "lock"))
+	r.NoError(err)
+
+	token, err := readCachedToken(ctx, tokenStorage, lock)
+	r.NoError(err)
+	r.Nil(token)
+
+	// what the oidc_cli cache stores, fresh just long enough to store it
+	expiry := time.Now().Add(5*time.Minute + 2*time.Second)
+	refresh := func(ctx context.Context, token *client.Token) (*client.Token, error) {
+		return &client.Token{IDToken: "cached", Expiry: expiry}, nil
+	}
+	_, err = cache.NewCache(tokenStorage, refresh, lock).Read(ctx)
+	r.NoError(err)
+
+	token, err = readCachedToken(ctx, tokenStorage, lock)
+	r.NoError(err)
+	r.Equal("cached", token.IDToken)
+
+	// we still get it once it's stale, without refreshing
+	time.Sleep(time.Until(expiry.Add(-5*time.Minute + 100*time.Millisecond)))
+	token, err = readCachedToken(ctx, tokenStorage, lock)
+	r.NoError(err)
+	r.False(token.IsFresh())
+	r.Equal("cached", token.IDToken)
+}
@@ -0,0 +1,142 @@
+package login
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/coreos/go-oidc"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// ErrDeviceFlowUnsupported means the issuer has no device authorization endpoint
+var ErrDeviceFlowUnsupported = errors.New("issuer does not support the device authorization grant")
+
+// DeviceFlow gets tokens with the OAuth 2.0 device authorization grant
+type DeviceFlow struct {
+	oauthConfig *oauth2.Config
+	verifier    *oidc.IDTokenVerifier
+	audience    string
+
+	out io.Writer
+}
+
+type deviceClaims struct {
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+}
+
+// NewDeviceFlow returns a new DeviceFlow. It fails if the issuer does not support the device flow.
+func NewDeviceFlow(ctx context.Context, conf *Config, out io.Writer) (*DeviceFlow, error) {
+	provider, err := oidc.NewProvider(ctx, conf.IssuerURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create oidc provider")
+	}
+
+	claims := &deviceClaims{}
+	err = provider.Claims(claims)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse oidc discovery document")
+	}
+	if claims.DeviceAuthorizationEndpoint == "" {
+		return nil, errors.Wrap(ErrDeviceFlowUnsupported, conf.IssuerURL)
+	}
+
+	endpoint := provider.Endpoint()
+	endpoint.DeviceAuthURL = claims.DeviceAuthorizationEndpoint
+
+	return &DeviceFlow{
+		oauthConfig: &oauth2.Config{
+			ClientID: conf.ClientID,
+			Endpoint: endpoint,
+			Scopes:   conf.scopes(),
+		},
+		verifier: provider.Verifier(&oidc.Config{
+			ClientID:             conf.ClientID,
+			SupportedSigningAlgs: []string{"RS256"},
+		}),
+		audience: conf.Audience,
+		out:      out,
+	}, nil
+}
+
+// RefreshToken refreshes oldToken if possible, otherwise it goes through the device flow
+func (d *DeviceFlow) RefreshToken(ctx context.Context, oldToken *client.Token) (*client.Token, error) {
+	if oldToken != nil && oldToken.RefreshToken != "" {
+		token, err := d.refresh(ctx, oldToken)
+		if err == nil {
+			return token, nil
+		}
+		logrus.WithError(err).Debug("failed to refresh token, requesting new one")
+	}
+	return d.Authenticate(ctx)
+}
+
+func (d *DeviceFlow) refresh(ctx context.Context, oldToken *client.Token) (*client.Token, error) {
+	oauthToken, err := d.oauthConfig.TokenSource(ctx, &oauth2.Token{
+		RefreshToken: oldToken.RefreshToken,
+		Expiry:       oldToken.Expiry,
+	}).Token()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not refresh token")
+	}
+	return d.toToken(ctx, oauthToken)
+}
+
+// Authenticate asks the user to visit a url and enter a code, then polls the issuer until they do
+func (d *DeviceFlow) Authenticate(ctx context.Context) (*client.Token, error) {
+	opts := []oauth2.AuthCodeOption{}
+	if d.audience != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("audience", d.audience))
+	}
+	deviceAuth, err := d.oauthConfig.DeviceAuth(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not start device authorization")
+	}
+
+	verificationURL := deviceAuth.VerificationURIComplete
+	if verificationURL == "" {
+		verificationURL = deviceAuth.VerificationURI
+	}
+	fmt.Fprintf(d.out, "To authenticate, visit %s and enter the code %s\n", verificationURL, deviceAuth.UserCode)
+
+	oauthToken, err := d.oauthConfig.DeviceAccessToken(ctx, deviceAuth)
+	if err != nil {
+		return nil, errors.Wrap(err, "device authorization failed")
+	}
+
+	token, err := d.toToken(ctx, oauthToken)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(d.out, "Successfully authenticated!\n")
+	return token, nil
+}
+
+func (d *DeviceFlow) toToken(ctx context.Context, oauthToken *oauth2.Token) (*client.Token, error) {
+	rawIDToken, ok := oauthToken.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("no id_token found in oauth2 token")
+	}
+
+	idToken, err := d.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not verify id token")
+	}
+
+	claims := client.Claims{}
+	err = idToken.Claims(&claims)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not verify claims")
+	}
+
+	return &client.Token{
+		Expiry:       idToken.Expiry,
+		IDToken:      rawIDToken,
+		AccessToken:  oauthToken.AccessToken,
+		RefreshToken: oauthToken.RefreshToken,
+		Claims:       claims,
+	}, nil
+}
@@ -0,0 +1,215 @@
+package login
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIssuer is a minimal oidc issuer supporting the device flow
+type fakeIssuer struct {
+	*httptest.Server
+
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu         sync.Mutex
+	polls      int
+	revoked    []string
+	deviceForm url.Values
+	// failRevoke makes revoking this token fail
+	failRevoke string
+	// noDevice leaves the device flow out of discovery
+	noDevice bool
+}
+
+func newFakeIssuer(r *require.Assertions, clientID string) *fakeIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	r.NoError(err)
+
+	f := &fakeIssuer{key: key, clientID: clientID}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, req *http.Request) {
+		discovery := map[string]string{
+			"issuer":                        f.URL,
+			"authorization_endpoint":        f.URL + "/authorize",
+			"token_endpoint":                f.URL + "/token",
+			"device_authorization_endpoint": f.URL + "/device",
+			"jwks_uri":                      f.URL + "/keys",
+			"revocation_endpoint":           f.URL + "/revoke",
+		}
+		f.mu.Lock()
+		if f.noDevice {
+			delete(discovery, "device_authorization_endpoint")
+		}
+		f.mu.Unlock()
+		writeJSON(w, discovery)
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/device", func(w http.ResponseWriter, req *http.Request) {
+		r.NoError(req.ParseForm())
+		f.mu.Lock()
+		f.deviceForm = req.Form
+		f.mu.Unlock()
+		writeJSON(w, map[string]interface{}{
+			"device_code":      "device-code",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": f.URL + "/activate",
+			"expires_in":       60,
+			"interval":         1,
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		r.NoError(req.ParseForm())
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		switch req.Form.Get("grant_type") {
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			f.polls++
+			if f.polls == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				writeJSON(w, map[string]string{"error": "authorization_pending"})
+				return
+			}
+		case "refresh_token":
+			if req.Form.Get("refresh_token") != "refresh-token" {
+				w.WriteHeader(http.StatusBadRequest)
+				writeJSON(w, map[string]string{"error": "invalid_grant"})
+				return
+			}
+		}
+
+		writeJSON(w, map[string]interface{}{
+			"access_token":  "access-token",
+			"token_type":    "Bearer",
+			"refresh_token": "refresh-token",
+			"expires_in":    3600,
+			"id_token":      f.idToken(r),
+		})
+	})
+
+	mux.HandleFunc("/revoke", func(w http.ResponseWriter, req *http.Request) {
+		r.NoError(req.ParseForm())
+		r.Equal(f.clientID, req.Form.Get("client_id"))
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if req.Form.Get("token") == f.failRevoke {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		f.revoked = append(f.revoked, req.Form.Get("token"))
+	})
+
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+func (f *fakeIssuer) idToken(r *require.Assertions) string {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	r.NoError(err)
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   f.URL,
+		"aud":   f.clientID,
+		"sub":   "user",
+		"email": "user@example.com",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+	r.NoError(err)
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, digest[:])
+	r.NoError(err)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
+
+func TestDeviceFlowAuthenticate(t *testing.T) {
+	r := require.New(t)
+	issuer := newFakeIssuer(r, "client-id")
+	defer issuer.Close()
+
+	out := bytes.NewBuffer(nil)
+	flow, err := NewDeviceFlow(context.Background(), &Config{ClientID: "client-id", IssuerURL: issuer.URL}, out)
+	r.NoError(err)
+
+	token, err := flow.RefreshToken(context.Background(), nil)
+	r.NoError(err)
+	r.Equal("access-token", token.AccessToken)
+	r.Equal("refresh-token", token.RefreshToken)
+	r.Equal("user@example.com", token.Claims.Email)
+	r.True(token.IsFresh())
+
+	r.Contains(out.String(), fmt.Sprintf("visit %s/activate and enter the code ABCD-EFGH", issuer.URL))
+	r.GreaterOrEqual(issuer.polls, 2)
+}
+
+func TestDeviceFlowRefresh(t *testing.T) {
+	r := require.New(t)
+	issuer := newFakeIssuer(r, "client-id")
+	defer issuer.Close()
+
+	out := bytes.NewBuffer(nil)
+	flow, err := NewDeviceFlow(context.Background(), &Config{ClientID: "client-id", IssuerURL: issuer.URL}, out)
+	r.NoError(err)
+
+	token, err := flow.RefreshToken(context.Background(), &client.Token{RefreshToken: "refresh-token"})
+	r.NoError(err)
+	r.Equal("access-token", token.AccessToken)
+	// we refreshed without asking the user for anything
+	r.Empty(out.String())
+	r.Equal(0, issuer.polls)
+}
+
+func TestDeviceFlowScopesAndAudience(t *testing.T) {
+	r := require.New(t)
+	issuer := newFakeIssuer(r, "client-id")
+	defer issuer.Close()
+
+	conf := &Config{
+		ClientID:  "client-id",
+		IssuerURL: issuer.URL,
+		Scopes:    []string{"openid", "groups"},
+		Audience:  "api://bless",
+	}
+	flow, err := NewDeviceFlow(context.Background(), conf, bytes.NewBuffer(nil))
+	r.NoError(err)
+
+	_, err = flow.Authenticate(context.Background())
+	r.NoError(err)
+	r.Equal("openid groups", issuer.deviceForm.Get("scope"))
+	r.Equal("api://bless", issuer.deviceForm.Get("audience"))
+}
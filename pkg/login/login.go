@@ -0,0 +1,237 @@
+// Package login acquires oidc tokens, either through the browser
+// or through the OAuth 2.0 device authorization grant (RFC 8628).
+package login
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/cache"
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/storage"
+	"github.com/chanzuckerberg/go-misc/osutil"
+	"github.com/chanzuckerberg/go-misc/pidlock"
+	"github.com/coreos/go-oidc"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// same lock oidc_cli uses so we never refresh concurrently with it
+	lockFilePath = "/tmp/aws-oidc.lock"
+
+	// same redirect port range and timeout as oidc_cli
+	defaultFromPort      = 49152
+	defaultToPort        = defaultFromPort + 63
+	defaultServerTimeout = 30 * time.Second
+)
+
+// same scopes oidc_cli requests
+var defaultScopes = []string{
+	oidc.ScopeOpenID,
+	oidc.ScopeOfflineAccess,
+	"email",
+	"groups",
+}
+
+// Method is how we log in when we don't have a usable cached token
+type Method string
+
+// Login methods
+const (
+	// MethodAuto uses the device flow when there is no display to open a browser on,
+	// unless the issuer doesn't support it
+	MethodAuto Method = "auto"
+	// MethodBrowser opens a browser and captures the redirect on localhost
+	MethodBrowser Method = "browser"
+	// MethodDevice prints a url and a code to enter on any other device
+	MethodDevice Method = "device"
+)
+
+// ParseMethod parses a login method
+func ParseMethod(method string) (Method, error) {
+	switch Method(method) {
+	case MethodAuto, MethodBrowser, MethodDevice:
+		return Method(method), nil
+	case "":
+		return MethodAuto, nil
+	default:
+		return "", errors.Errorf("unknown login method %s, expected one of auto, browser, device", method)
+	}
+}
+
+// Config configures how we get tokens
+type Config struct {
+	ClientID  string
+	IssuerURL string
+	Method    Method
+
+	// Scopes to request, defaults to openid, offline_access, email and groups
+	Scopes []string
+	// Audience, if set, is sent as the audience parameter when logging in
+	// for issuers that mint access tokens per audience
+	Audience string
+	// RedirectPort pins the localhost port the browser flow redirects to.
+	// Defaults to the first free port in a range.
+	RedirectPort int
+
+	// MinTTL forces a refresh if the cached token expires sooner than this
+	MinTTL time.Duration
+
+	// Out is where we print device flow instructions, defaults to stderr
+	Out io.Writer
+}
+
+// GetToken gets an oidc token.
+// Tokens are cached in the same storage oidc_cli uses, regardless of how we logged in.
+func GetToken(ctx context.Context, conf *Config) (*client.Token, error) {
+	refresh := conf.refresher()
+
+	fileLock, err := pidlock.NewLock(lockFilePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create lock")
+	}
+
+	tokenStorage, err := storage.GetOIDC(conf.ClientID, conf.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := cache.NewCache(tokenStorage, refresh, fileLock).Read(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to extract token from client")
+	}
+	if token == nil {
+		return nil, errors.New("nil token from OIDC-IDP")
+	}
+
+	if conf.MinTTL > 0 && token.Expiry.Before(time.Now().Add(conf.MinTTL)) {
+		logrus.Debugf("token expires at %s, refreshing to get at least %s", token.Expiry, conf.MinTTL)
+		return forceRefresh(ctx, tokenStorage, fileLock, refresh, token)
+	}
+	return token, nil
+}
+
+// refresher returns a function that refreshes a token,
+// logging in with the configured method if that fails.
+// The login flow is only set up once we need it, so a cached token
+// works even when the issuer can't be reached.
+func (conf *Config) refresher() refreshFunc {
+	return func(ctx context.Context, oldToken *client.Token) (*client.Token, error) {
+		refresh, err := conf.newRefresher(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return refresh(ctx, oldToken)
+	}
+}
+
+func (conf *Config) newRefresher(ctx context.Context) (refreshFunc, error) {
+	method := conf.Method
+	auto := method == MethodAuto || method == ""
+	if auto {
+		method = MethodBrowser
+		if !HasDisplay() {
+			logrus.Debug("no display available, using the device flow to log in")
+			method = MethodDevice
+		}
+	}
+
+	if method == MethodDevice {
+		out := conf.Out
+		if out == nil {
+			out = os.Stderr
+		}
+		flow, err := NewDeviceFlow(ctx, conf, out)
+		if err == nil {
+			return flow.RefreshToken, nil
+		}
+		if !auto || !errors.Is(err, ErrDeviceFlowUnsupported) {
+			return nil, err
+		}
+		logrus.WithError(err).Debug("falling back to the browser flow to log in")
+	}
+	return conf.browserRefresher(ctx)
+}
+
+// browserRefresher returns oidc_cli's browser flow
+func (conf *Config) browserRefresher(ctx context.Context) (refreshFunc, error) {
+	serverConfig := &client.ServerConfig{
+		FromPort: defaultFromPort,
+		ToPort:   defaultToPort,
+		Timeout:  defaultServerTimeout,
+	}
+	if conf.RedirectPort != 0 {
+		serverConfig.FromPort = conf.RedirectPort
+		serverConfig.ToPort = conf.RedirectPort
+	}
+
+	c, err := client.NewClient(
+		ctx,
+		&client.Config{
+			ClientID:     conf.ClientID,
+			IssuerURL:    conf.IssuerURL,
+			ServerConfig: serverConfig,
+		},
+		client.SetScopeOptions(conf.scopes()),
+		setAudience(conf.Audience),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to create client")
+	}
+	return c.RefreshToken, nil
+}
+
+func (conf *Config) scopes() []string {
+	if len(conf.Scopes) == 0 {
+		return defaultScopes
+	}
+	return conf.Scopes
+}
+
+// setAudience adds the audience parameter to the authorization url.
+// oidc_cli builds the url itself so we can't pass it as an AuthCodeOption.
+func setAudience(audience string) client.Option {
+	return func(c *client.Client) {
+		if audience == "" {
+			return
+		}
+		authURL, err := url.Parse(c.OauthConfig.Endpoint.AuthURL)
+		if err != nil {
+			logrus.WithError(err).Warn("could not parse authorization url, not setting the audience")
+			return
+		}
+		q := authURL.Query()
+		q.Set("audience", audience)
+		authURL.RawQuery = q.Encode()
+		c.OauthConfig.Endpoint.AuthURL = authURL.String()
+	}
+}
+
+// HasDisplay returns true if we can probably open a browser the user can see
+func HasDisplay() bool {
+	// a browser opened on a remote machine is of no use to the user
+	if os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "" {
+		return false
+	}
+
+	switch runtime.GOOS {
+	case "darwin", "windows":
+		return true
+	}
+
+	if os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != "" {
+		return true
+	}
+	// WSL opens the browser on the windows side
+	isWSL, err := osutil.IsWSL()
+	if err != nil {
+		logrus.WithError(err).Debug("could not detect WSL")
+		return false
+	}
+	return isWSL
+}
@@ -0,0 +1,100 @@
+package login
+
+import (
+	"bytes"
+	"context"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/storage"
+	"github.com/chanzuckerberg/go-misc/pidlock"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/oauth2"
+)
+
+func TestParseMethod(t *testing.T) {
+	r := require.New(t)
+
+	for _, method := range []string{"auto", "browser", "device"} {
+		m, err := ParseMethod(method)
+		r.NoError(err)
+		r.Equal(Method(method), m)
+	}
+
+	m, err := ParseMethod("")
+	r.NoError(err)
+	r.Equal(MethodAuto, m)
+
+	_, err = ParseMethod("carrier-pigeon")
+	r.Error(err)
+}
+
+func TestHasDisplayOverSSH(t *testing.T) {
+	r := require.New(t)
+	t.Setenv("DISPLAY", ":0")
+	t.Setenv("SSH_CONNECTION", "10.0.0.1 22 10.0.0.2 22")
+	r.False(HasDisplay())
+}
+
+func TestSetAudience(t *testing.T) {
+	r := require.New(t)
+	c := &client.Client{OauthConfig: &oauth2.Config{
+		Endpoint: oauth2.Endpoint{AuthURL: "https://issuer.example.com/authorize"},
+	}}
+
+	setAudience("")(c)
+	r.Equal("https://issuer.example.com/authorize", c.OauthConfig.Endpoint.AuthURL)
+
+	setAudience("api://bless")(c)
+	r.Equal("https://issuer.example.com/authorize?audience=api%3A%2F%2Fbless", c.OauthConfig.Endpoint.AuthURL)
+	// oauth2 appends its own parameters to ours
+	r.Contains(c.OauthConfig.AuthCodeURL("state"), "audience=api%3A%2F%2Fbless&")
+}
+
+func TestGetTokenCachedWithoutIssuer(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	keyring.MockInit()
+	t.Setenv("SSH_CONNECTION", "10.0.0.1 22 10.0.0.2 22")
+
+	// nothing listens here, we must not need the issuer for a cached token
+	conf := &Config{ClientID: "client-id", IssuerURL: "http://127.0.0.1:1", Method: MethodAuto}
+	tokenStorage, err := storage.GetOIDC(conf.ClientID, conf.IssuerURL)
+	r.NoError(err)
+	lock, err := pidlock.NewLock(path.Join(t.TempDir(), "lock"))
+	r.NoError(err)
+	cacheToken := func(ctx context.Context, token *client.Token) (*client.Token, error) {
+		return &client.Token{IDToken: "cached", Expiry: time.Now().Add(time.Hour)}, nil
+	}
+	_, err = forceRefresh(ctx, tokenStorage, lock, cacheToken, nil)
+	r.NoError(err)
+
+	token, err := GetToken(ctx, conf)
+	r.NoError(err)
+	r.Equal("cached", token.IDToken)
+}
+
+func TestRefresherDeviceFlowUnsupported(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	issuer := newFakeIssuer(r, "client-id")
+	defer issuer.Close()
+	issuer.noDevice = true
+	t.Setenv("SSH_CONNECTION", "10.0.0.1 22 10.0.0.2 22")
+
+	// auto falls back to the browser
+	conf := &Config{ClientID: "client-id", IssuerURL: issuer.URL, Method: MethodAuto, Out: bytes.NewBuffer(nil)}
+	refresh, err := conf.newRefresher(ctx)
+	r.NoError(err)
+	r.NotNil(refresh)
+
+	// asking for the device flow explicitly fails
+	conf.Method = MethodDevice
+	_, err = conf.newRefresher(ctx)
+	r.Error(err)
+	r.True(errors.Is(err, ErrDeviceFlowUnsupported))
+}
@@ -0,0 +1,118 @@
+package login
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/storage"
+	"github.com/chanzuckerberg/go-misc/pidlock"
+	"github.com/coreos/go-oidc"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type revocationClaims struct {
+	RevocationEndpoint string `json:"revocation_endpoint"`
+}
+
+// Logout revokes the cached tokens for conf, if the issuer supports it,
+// and removes them from the cache.
+func Logout(ctx context.Context, conf *Config) error {
+	tokenStorage, err := storage.GetOIDC(conf.ClientID, conf.IssuerURL)
+	if err != nil {
+		return err
+	}
+	fileLock, err := pidlock.NewLock(lockFilePath)
+	if err != nil {
+		return errors.Wrap(err, "unable to create lock")
+	}
+	return logout(ctx, tokenStorage, fileLock, conf)
+}
+
+func logout(ctx context.Context, tokenStorage storage.Storage, lock *pidlock.Lock, conf *Config) error {
+	token, err := readCachedToken(ctx, tokenStorage, lock)
+	if err != nil {
+		// we still want to get rid of whatever is in there
+		logrus.WithError(err).Debug("could not read cached token, removing it anyway")
+	}
+
+	if token != nil {
+		// revoking is best effort, the cached tokens go away regardless
+		err = revoke(ctx, conf, token)
+		if err != nil {
+			logrus.WithError(err).Warnf("could not revoke tokens with %s", conf.IssuerURL)
+		}
+	}
+
+	err = tokenStorage.Delete(ctx)
+	// file storage complains when there is nothing to delete
+	if err != nil && !os.IsNotExist(errors.Cause(err)) {
+		return errors.Wrapf(err, "could not remove cached tokens for %s", conf.IssuerURL)
+	}
+	return nil
+}
+
+// revoke revokes token at the issuer's revocation endpoint (RFC 7009)
+func revoke(ctx context.Context, conf *Config, token *client.Token) error {
+	provider, err := oidc.NewProvider(ctx, conf.IssuerURL)
+	if err != nil {
+		return errors.Wrap(err, "could not create oidc provider")
+	}
+
+	claims := &revocationClaims{}
+	err = provider.Claims(claims)
+	if err != nil {
+		return errors.Wrap(err, "could not parse oidc discovery document")
+	}
+	if claims.RevocationEndpoint == "" {
+		logrus.Debugf("%s has no revocation endpoint, not revoking tokens", conf.IssuerURL)
+		return nil
+	}
+
+	// revoking the refresh token usually revokes the access tokens minted from it too,
+	// but we still try the access token if that fails
+	tokens := map[string]string{
+		"refresh_token": token.RefreshToken,
+		"access_token":  token.AccessToken,
+	}
+	var errs *multierror.Error
+	for _, hint := range []string{"refresh_token", "access_token"} {
+		if tokens[hint] == "" {
+			continue
+		}
+		err = revokeToken(ctx, claims.RevocationEndpoint, conf.ClientID, tokens[hint], hint)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+func revokeToken(ctx context.Context, endpoint string, clientID string, token string, hint string) error {
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {hint},
+		"client_id":       {clientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.Wrap(err, "could not create revocation request")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "could not revoke %s", hint)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("revoking %s returned %s", hint, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package login
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/client"
+	"github.com/chanzuckerberg/go-misc/oidc_cli/oidc_impl/storage"
+	"github.com/chanzuckerberg/go-misc/pidlock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogout(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	issuer := newFakeIssuer(r, "client-id")
+	defer issuer.Close()
+
+	dir, err := ioutil.TempDir("", "blessclient-logout")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	conf := &Config{ClientID: "client-id", IssuerURL: issuer.URL}
+	tokenStorage := storage.NewFile(dir, conf.ClientID, conf.IssuerURL)
+	lock, err := pidlock.NewLock(path.Join(dir, "lock"))
+	r.NoError(err)
+
+	// cache a token
+	refresh := func(ctx context.Context, token *client.Token) (*client.Token, error) {
+		return &client.Token{
+			IDToken:      "id-token",
+			AccessToken:  "access-token",
+			RefreshToken: "refresh-token",
+			Expiry:       time.Now().Add(time.Hour),
+		}, nil
+	}
+	_, err = forceRefresh(ctx, tokenStorage, lock, refresh, nil)
+	r.NoError(err)
+
+	r.NoError(logout(ctx, tokenStorage, lock, conf))
+	cached, err := tokenStorage.Read(ctx)
+	r.NoError(err)
+	r.Nil(cached)
+	// file storage never persists refresh tokens
+	r.Equal([]string{"access-token"}, issuer.revoked)
+
+	// logging out twice is fine
+	r.NoError(logout(ctx, tokenStorage, lock, conf))
+	r.Len(issuer.revoked, 1)
+}
+
+func TestRevokeBothTokens(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	issuer := newFakeIssuer(r, "client-id")
+	defer issuer.Close()
+	issuer.failRevoke = "refresh-token"
+
+	conf := &Config{ClientID: "client-id", IssuerURL: issuer.URL}
+	token := &client.Token{
+		AccessToken:  "access-token",
+		RefreshToken: "refresh-token",
+	}
+
+	err := revoke(ctx, conf, token)
+	r.Error(err)
+	r.Contains(err.Error(), "revoking refresh_token returned 503")
+	// the access token is revoked anyway
+	r.Equal([]string{"access-token"}, issuer.revoked)
+}
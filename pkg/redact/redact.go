@@ -0,0 +1,115 @@
+// Package redact replaces secrets with fingerprints so logs can be shared
+// without leaking tokens, private keys or certificates. A fingerprint is
+// stable, so the same token can still be followed through a log.
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// fields whose values are always secret, matched case insensitively
+var secretFields = []string{
+	"access_token",
+	"accesstoken",
+	"id_token",
+	"idtoken",
+	"refresh_token",
+	"refreshtoken",
+	"token",
+	"session_token",
+	"sessiontoken",
+	"secret_access_key",
+	"secretaccesskey",
+	"webidentitytoken",
+	"web_identity_token",
+	"client_secret",
+	"cert",
+	"private_key",
+}
+
+var (
+	privateKeyPattern = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)
+	// three base64url segments, the first of which is a json object
+	jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	// ssh keys and certificates in authorized_keys format
+	sshKeyPattern = regexp.MustCompile(`((?:ssh|ecdsa|sk)-[a-z0-9@.-]+) (AAAA[A-Za-z0-9+/]+=*)`)
+	// "field": "value" in json, also when the json is itself quoted in a json log line
+	jsonFieldPattern = regexp.MustCompile(
+		`(\\?"(?i:` + strings.Join(secretFields, "|") + `)\\?"\s*:\s*\\?")([^"\\]+)`)
+	// field=value in logrus' text format
+	textFieldPattern = regexp.MustCompile(
+		`(\b(?i:` + strings.Join(secretFields, "|") + `)="?)([^\s"]+)`)
+)
+
+// Fingerprint returns a short identifier for secret that doesn't reveal it
+func Fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// Certificate describes cert without its body
+func Certificate(cert *ssh.Certificate) string {
+	if cert == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf(
+		"cert(key_id=%q serial=%d principals=%s key=%s)",
+		cert.KeyId,
+		cert.Serial,
+		strings.Join(cert.ValidPrincipals, ","),
+		ssh.FingerprintSHA256(cert.Key),
+	)
+}
+
+// String replaces every secret we recognize in s with its fingerprint
+func String(s string) string {
+	s = privateKeyPattern.ReplaceAllStringFunc(s, func(key string) string {
+		return "[private key " + Fingerprint(key) + "]"
+	})
+	s = jwtPattern.ReplaceAllStringFunc(s, func(token string) string {
+		return "[jwt " + Fingerprint(token) + "]"
+	})
+	s = replaceGroup(sshKeyPattern, s, func(blob string) string {
+		return "[" + Fingerprint(blob) + "]"
+	})
+	s = replaceGroup(jsonFieldPattern, s, redactValue)
+	return replaceGroup(textFieldPattern, s, redactValue)
+}
+
+// Bytes is String for byte slices
+func Bytes(b []byte) []byte {
+	return []byte(String(string(b)))
+}
+
+// redactValue fingerprints a field value unless an earlier pattern already did
+func redactValue(value string) string {
+	if strings.HasPrefix(value, "[") {
+		return value
+	}
+	return "[" + Fingerprint(value) + "]"
+}
+
+// replaceGroup replaces the last capture group of every match of re in s
+func replaceGroup(re *regexp.Regexp, s string, replace func(string) string) string {
+	matches := re.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[len(m)-2], m[len(m)-1]
+		b.WriteString(s[last:start])
+		b.WriteString(replace(s[start:end]))
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
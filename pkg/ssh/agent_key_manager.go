@@ -68,20 +68,10 @@ func (a *AgentKeyManager) ListCertificates() ([]*ssh.Certificate, error) {
 			continue
 		}
 
-		_, ok = cert.Extensions["ssh-ca-lambda"]
-		if !ok {
-			// not a certificate we care about
+		if !isValidBlessCertificate(cert, time.Now()) {
 			continue
 		}
 
-		now := time.Now()
-		validAfter := time.Unix(int64(cert.ValidAfter), 0)
-		validBefore := time.Unix(int64(cert.ValidBefore), 0)
-
-		if !(now.After(validAfter) && now.Before(validBefore)) {
-			continue // expired
-		}
-
 		allCerts = append(allCerts, cert)
 	}
 
@@ -96,3 +86,30 @@ func (a *AgentKeyManager) HasValidCertificate() (bool, error) {
 
 	return len(certs) > 0, nil
 }
+
+// RemoveCertificates removes every blessclient certificate from the agent
+func (a *AgentKeyManager) RemoveCertificates() (int, error) {
+	agentKeys, err := a.agent.List()
+	if err != nil {
+		return 0, errors.Wrap(err, "could not list agent keys")
+	}
+
+	removed := 0
+	for _, agentKey := range agentKeys {
+		pub, err := ssh.ParsePublicKey(agentKey.Marshal())
+		if err != nil {
+			continue
+		}
+		cert, ok := pub.(*ssh.Certificate)
+		if !ok || !IsBlessCertificate(cert) {
+			continue
+		}
+
+		err = a.agent.Remove(cert)
+		if err != nil {
+			return removed, errors.Wrap(err, "could not remove certificate from agent")
+		}
+		removed++
+	}
+	return removed, nil
+}
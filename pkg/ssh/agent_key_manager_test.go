@@ -0,0 +1,44 @@
+package ssh_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	cziSSH "github.com/chanzuckerberg/blessclient/pkg/ssh"
+	"github.com/chanzuckerberg/blessclient/pkg/ssh/sshtest"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func TestAgentKeyManagerRemoveCertificates(t *testing.T) {
+	r := require.New(t)
+	keyring := agent.NewKeyring().(agent.ExtendedAgent)
+
+	// a key that isn't ours
+	_, otherPriv, err := ed25519.GenerateKey(rand.Reader)
+	r.NoError(err)
+	r.NoError(keyring.Add(agent.AddedKey{PrivateKey: otherPriv}))
+
+	manager := cziSSH.NewAgentKeyManager(keyring)
+	pub, priv, err := manager.GetKey()
+	r.NoError(err)
+	r.NoError(manager.WriteKey(priv, sshtest.NewCert(r, pub.(ed25519.PublicKey), time.Now().Add(-time.Minute), time.Now().Add(time.Hour))))
+
+	hasCert, err := manager.HasValidCertificate()
+	r.NoError(err)
+	r.True(hasCert)
+
+	removed, err := manager.RemoveCertificates()
+	r.NoError(err)
+	r.Equal(1, removed)
+
+	hasCert, err = manager.HasValidCertificate()
+	r.NoError(err)
+	r.False(hasCert)
+
+	keys, err := keyring.List()
+	r.NoError(err)
+	r.Len(keys, 1)
+}
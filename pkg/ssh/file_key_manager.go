@@ -0,0 +1,163 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+// FileKeyManager writes keys and certificates to disk
+// for environments without an ssh agent.
+// The certificate is written next to the key as <key>-cert.pub so ssh picks it up
+// with `-i <key>`.
+type FileKeyManager struct {
+	keyPath string
+}
+
+// NewFileKeyManager returns a key manager that writes to keyPath
+func NewFileKeyManager(keyPath string) (*FileKeyManager, error) {
+	expandedPath, err := homedir.Expand(keyPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not expand %s", keyPath)
+	}
+	return &FileKeyManager{keyPath: expandedPath}, nil
+}
+
+// CertPath returns the path we write the certificate to
+func (f *FileKeyManager) CertPath() string {
+	return f.keyPath + "-cert.pub"
+}
+
+// GetKey will generate new ssh keypair
+func (f *FileKeyManager) GetKey() (crypto.PublicKey, crypto.PrivateKey, error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	return public, private, errors.Wrap(err, "could not generate ed25519 keys")
+}
+
+// WriteKey will write the key and certificate to disk
+func (f *FileKeyManager) WriteKey(
+	priv crypto.PrivateKey,
+	cert *ssh.Certificate,
+) error {
+	block, err := ssh.MarshalPrivateKey(priv, "blessclient")
+	if err != nil {
+		return errors.Wrap(err, "could not marshal private key")
+	}
+
+	err = os.MkdirAll(path.Dir(f.keyPath), 0700)
+	if err != nil {
+		return errors.Wrapf(err, "could not create %s", path.Dir(f.keyPath))
+	}
+
+	// Each file is replaced atomically but not the pair, so a crash or a concurrent
+	// reader can see the new key next to the old cert. ListCertificates only trusts
+	// a cert that matches the key on disk, so we mint a new one if that happens.
+	err = util.WriteFileAtomic(f.keyPath, pem.EncodeToMemory(block), 0600)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(f.CertPath(), ssh.MarshalAuthorizedKey(cert), 0644)
+}
+
+// ListCertificates returns the certificate on disk if it is valid
+// and certifies the key next to it
+func (f *FileKeyManager) ListCertificates() ([]*ssh.Certificate, error) {
+	data, err := ioutil.ReadFile(f.CertPath())
+	if os.IsNotExist(err) {
+		return []*ssh.Certificate{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read %s", f.CertPath())
+	}
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse %s", f.CertPath())
+	}
+
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok || !isValidBlessCertificate(cert, time.Now()) {
+		return []*ssh.Certificate{}, nil
+	}
+
+	matches, err := f.certifiesKey(cert)
+	if err != nil {
+		return nil, err
+	}
+	if !matches {
+		logrus.Debugf("%s does not certify %s, ignoring it", f.CertPath(), f.keyPath)
+		return []*ssh.Certificate{}, nil
+	}
+	return []*ssh.Certificate{cert}, nil
+}
+
+// certifiesKey returns true if cert is for the private key on disk
+func (f *FileKeyManager) certifiesKey(cert *ssh.Certificate) (bool, error) {
+	data, err := ioutil.ReadFile(f.keyPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "could not read %s", f.keyPath)
+	}
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		logrus.WithError(err).Debugf("could not parse %s", f.keyPath)
+		return false, nil
+	}
+	return bytes.Equal(signer.PublicKey().Marshal(), cert.Key.Marshal()), nil
+}
+
+// HasValidCertificate returns true if there is a valid certificate on disk
+func (f *FileKeyManager) HasValidCertificate() (bool, error) {
+	certs, err := f.ListCertificates()
+	if err != nil {
+		return false, err
+	}
+	return len(certs) > 0, nil
+}
+
+// RemoveCertificates removes the certificate and its key from disk
+// if the certificate was minted by the CA
+func (f *FileKeyManager) RemoveCertificates() (int, error) {
+	data, err := ioutil.ReadFile(f.CertPath())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not read %s", f.CertPath())
+	}
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not parse %s", f.CertPath())
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok || !IsBlessCertificate(cert) {
+		// not ours, leave it alone
+		return 0, nil
+	}
+
+	// remove the cert first so we never have a cert without its key
+	err = os.Remove(f.CertPath())
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not remove %s", f.CertPath())
+	}
+	err = os.Remove(f.keyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return 1, errors.Wrapf(err, "could not remove %s", f.keyPath)
+	}
+	return 1, nil
+}
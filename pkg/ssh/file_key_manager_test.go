@@ -0,0 +1,121 @@
+package ssh_test
+
+import (
+	"crypto/ed25519"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	cziSSH "github.com/chanzuckerberg/blessclient/pkg/ssh"
+	"github.com/chanzuckerberg/blessclient/pkg/ssh/sshtest"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestFileKeyManager(t *testing.T) {
+	r := require.New(t)
+
+	dir, err := ioutil.TempDir("", "blessclient-file-key-manager")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	manager, err := cziSSH.NewFileKeyManager(path.Join(dir, "id_ed25519"))
+	r.NoError(err)
+
+	hasCert, err := manager.HasValidCertificate()
+	r.NoError(err)
+	r.False(hasCert)
+
+	pub, priv, err := manager.GetKey()
+	r.NoError(err)
+	cert := sshtest.NewCert(r, pub.(ed25519.PublicKey), time.Now().Add(-time.Minute), time.Now().Add(time.Hour))
+
+	r.NoError(manager.WriteKey(priv, cert))
+
+	hasCert, err = manager.HasValidCertificate()
+	r.NoError(err)
+	r.True(hasCert)
+
+	// ssh can read back what we wrote
+	keyBytes, err := ioutil.ReadFile(path.Join(dir, "id_ed25519"))
+	r.NoError(err)
+	signer, err := ssh.ParsePrivateKey(keyBytes)
+	r.NoError(err)
+	r.Equal(cert.Key.Marshal(), signer.PublicKey().Marshal())
+
+	info, err := os.Stat(path.Join(dir, "id_ed25519"))
+	r.NoError(err)
+	r.Equal(os.FileMode(0600), info.Mode().Perm())
+	r.Equal(path.Join(dir, "id_ed25519-cert.pub"), manager.CertPath())
+}
+
+func TestFileKeyManagerMismatchedKey(t *testing.T) {
+	r := require.New(t)
+	manager, err := cziSSH.NewFileKeyManager(path.Join(t.TempDir(), "id_ed25519"))
+	r.NoError(err)
+
+	pub, priv, err := manager.GetKey()
+	r.NoError(err)
+	r.NoError(manager.WriteKey(priv, sshtest.NewCert(r, pub.(ed25519.PublicKey), time.Now().Add(-time.Minute), time.Now().Add(time.Hour))))
+
+	// we crashed between writing a new key and its cert
+	_, otherPriv, err := manager.GetKey()
+	r.NoError(err)
+	block, err := ssh.MarshalPrivateKey(otherPriv, "")
+	r.NoError(err)
+	r.NoError(ioutil.WriteFile(path.Join(path.Dir(manager.CertPath()), "id_ed25519"), pem.EncodeToMemory(block), 0600))
+
+	hasCert, err := manager.HasValidCertificate()
+	r.NoError(err)
+	r.False(hasCert)
+}
+
+func TestFileKeyManagerExpired(t *testing.T) {
+	r := require.New(t)
+
+	dir, err := ioutil.TempDir("", "blessclient-file-key-manager")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	manager, err := cziSSH.NewFileKeyManager(path.Join(dir, "id_ed25519"))
+	r.NoError(err)
+
+	pub, priv, err := manager.GetKey()
+	r.NoError(err)
+	r.NoError(manager.WriteKey(priv, sshtest.NewCert(r, pub.(ed25519.PublicKey), time.Now().Add(-time.Minute), time.Now().Add(-time.Second))))
+
+	hasCert, err := manager.HasValidCertificate()
+	r.NoError(err)
+	r.False(hasCert)
+}
+
+func TestFileKeyManagerRemoveCertificates(t *testing.T) {
+	r := require.New(t)
+
+	dir, err := ioutil.TempDir("", "blessclient-file-key-manager")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	manager, err := cziSSH.NewFileKeyManager(path.Join(dir, "id_ed25519"))
+	r.NoError(err)
+
+	removed, err := manager.RemoveCertificates()
+	r.NoError(err)
+	r.Equal(0, removed)
+
+	pub, priv, err := manager.GetKey()
+	r.NoError(err)
+	r.NoError(manager.WriteKey(priv, sshtest.NewCert(r, pub.(ed25519.PublicKey), time.Now().Add(-time.Minute), time.Now().Add(time.Hour))))
+
+	removed, err = manager.RemoveCertificates()
+	r.NoError(err)
+	r.Equal(1, removed)
+
+	_, err = os.Stat(manager.CertPath())
+	r.True(os.IsNotExist(err))
+	_, err = os.Stat(path.Join(dir, "id_ed25519"))
+	r.True(os.IsNotExist(err))
+}
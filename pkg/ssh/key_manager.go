@@ -2,13 +2,40 @@ package ssh
 
 import (
 	"crypto"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 )
 
+const (
+	// blessExtension is set on every certificate minted by the CA
+	blessExtension = "ssh-ca-lambda"
+)
+
 type KeyManager interface {
 	GetKey() (crypto.PublicKey, crypto.PrivateKey, error)
 	WriteKey(crypto.PrivateKey, *ssh.Certificate) error
 	HasValidCertificate() (bool, error)
 	ListCertificates() ([]*ssh.Certificate, error)
+	// RemoveCertificates removes every certificate minted by the CA, expired or not,
+	// and returns how many it removed
+	RemoveCertificates() (int, error)
+}
+
+// IsBlessCertificate returns true if cert was minted by the CA
+func IsBlessCertificate(cert *ssh.Certificate) bool {
+	_, ok := cert.Extensions[blessExtension]
+	return ok
+}
+
+// isValidBlessCertificate returns true if cert was minted by the CA and is valid at now
+func isValidBlessCertificate(cert *ssh.Certificate, now time.Time) bool {
+	if !IsBlessCertificate(cert) {
+		// not a certificate we care about
+		return false
+	}
+
+	validAfter := time.Unix(int64(cert.ValidAfter), 0)
+	validBefore := time.Unix(int64(cert.ValidBefore), 0)
+	return now.After(validAfter) && now.Before(validBefore)
 }
@@ -0,0 +1,44 @@
+// Package sshtest mints ssh certificates for tests.
+package sshtest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+// KeyID is the key id of the certificates NewCert mints
+const KeyID = "user@example.com"
+
+// NewCert returns a certificate for pub valid from validAfter until validBefore,
+// signed by a throwaway CA and marked like the ones the CA mints
+func NewCert(r *require.Assertions, pub ed25519.PublicKey, validAfter time.Time, validBefore time.Time) *ssh.Certificate {
+	_, caPriv, err := ed25519.GenerateKey(rand.Reader)
+	r.NoError(err)
+	caSigner, err := ssh.NewSignerFromKey(caPriv)
+	r.NoError(err)
+	sshPub, err := ssh.NewPublicKey(pub)
+	r.NoError(err)
+
+	cert := &ssh.Certificate{
+		Key:             sshPub,
+		CertType:        ssh.UserCert,
+		KeyId:           KeyID,
+		ValidPrincipals: []string{"user"},
+		ValidAfter:      uint64(validAfter.Unix()),
+		ValidBefore:     uint64(validBefore.Unix()),
+		Permissions:     ssh.Permissions{Extensions: map[string]string{"ssh-ca-lambda": ""}},
+	}
+	r.NoError(cert.SignCert(rand.Reader, caSigner))
+	return cert
+}
+
+// NewKeyAndCert returns a new key and a certificate for it, see NewCert
+func NewKeyAndCert(r *require.Assertions, validAfter time.Time, validBefore time.Time) (*ssh.Certificate, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	r.NoError(err)
+	return NewCert(r, pub, validAfter, validBefore), priv
+}
@@ -0,0 +1,102 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultGitHubRepo is where blessclient is released
+	DefaultGitHubRepo = "chanzuckerberg/blessclient"
+
+	defaultGitHubAPI = "https://api.github.com"
+)
+
+// GitHubSource finds releases published by goreleaser on GitHub
+type GitHubSource struct {
+	repo   string
+	apiURL string
+	client *http.Client
+}
+
+// NewGitHubSource returns a source for the GitHub repo owner/name
+func NewGitHubSource(repo string) *GitHubSource {
+	return &GitHubSource{
+		repo:   repo,
+		apiURL: defaultGitHubAPI,
+		client: http.DefaultClient,
+	}
+}
+
+type gitHubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// Latest returns the latest GitHub release, prereleases and drafts are skipped by GitHub
+func (g *GitHubSource) Latest(ctx context.Context) (*Release, error) {
+	latestURL := fmt.Sprintf("%s/repos/%s/releases/latest", g.apiURL, g.repo)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestURL, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create request for %s", latestURL)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get %s", latestURL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("could not get %s: %s", latestURL, resp.Status)
+	}
+
+	gh := &gitHubRelease{}
+	err = json.NewDecoder(resp.Body).Decode(gh)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not decode %s", latestURL)
+	}
+	return gh.release()
+}
+
+// release finds the goreleaser artifacts for this platform in gh
+func (gh *gitHubRelease) release() (*Release, error) {
+	version, err := semver.ParseTolerant(gh.TagName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse release tag %s", gh.TagName)
+	}
+
+	release := &Release{Version: version}
+
+	// names goreleaser gives the artifacts
+	archiveName := release.ArchiveName()
+	checksumsName := fmt.Sprintf("blessclient_%s_checksums.txt", version)
+	signatureName := checksumsName + ".sig"
+
+	for _, asset := range gh.Assets {
+		switch asset.Name {
+		case archiveName:
+			release.ArchiveURL = asset.URL
+		case checksumsName:
+			release.ChecksumsURL = asset.URL
+		case signatureName:
+			release.SignatureURL = asset.URL
+		}
+	}
+
+	if release.ArchiveURL == "" {
+		return nil, errors.Errorf("release %s has no %s", gh.TagName, archiveName)
+	}
+	if release.ChecksumsURL == "" || release.SignatureURL == "" {
+		return nil, errors.Errorf("release %s is not signed", gh.TagName)
+	}
+	return release, nil
+}
@@ -0,0 +1,63 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubSourceLatest(t *testing.T) {
+	r := require.New(t)
+	assets := fmt.Sprintf(`[
+		{"name": "blessclient_1.2.0_%s.tar.gz", "browser_download_url": "https://example.com/archive"},
+		{"name": "blessclient_1.2.0_plan9_386.tar.gz", "browser_download_url": "https://example.com/plan9"},
+		{"name": "blessclient_1.2.0_checksums.txt", "browser_download_url": "https://example.com/checksums"},
+		{"name": "blessclient_1.2.0_checksums.txt.sig", "browser_download_url": "https://example.com/signature"}
+	]`, Platform)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.Equal("/repos/chanzuckerberg/blessclient/releases/latest", req.URL.Path)
+		fmt.Fprintf(w, `{"tag_name": "v1.2.0", "assets": %s}`, assets)
+	}))
+	defer server.Close()
+
+	source := NewGitHubSource(DefaultGitHubRepo)
+	source.apiURL = server.URL
+	release, err := source.Latest(context.Background())
+	r.NoError(err)
+	r.Equal("1.2.0", release.Version.String())
+	r.Equal("https://example.com/archive", release.ArchiveURL)
+	r.Equal("https://example.com/checksums", release.ChecksumsURL)
+	r.Equal("https://example.com/signature", release.SignatureURL)
+}
+
+func TestGitHubReleaseUnsigned(t *testing.T) {
+	r := require.New(t)
+	gh := &gitHubRelease{TagName: "v1.2.0"}
+	gh.Assets = append(gh.Assets, struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	}{Name: fmt.Sprintf("blessclient_1.2.0_%s.tar.gz", Platform), URL: "https://example.com/archive"})
+
+	_, err := gh.release()
+	r.Error(err)
+	r.Contains(err.Error(), "release v1.2.0 is not signed")
+}
+
+func TestGitHubSourceError(t *testing.T) {
+	r := require.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	source := NewGitHubSource(DefaultGitHubRepo)
+	source.apiURL = server.URL
+	_, err := source.Latest(context.Background())
+	r.Error(err)
+	r.Contains(err.Error(), "403 Forbidden")
+}
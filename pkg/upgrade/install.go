@@ -0,0 +1,165 @@
+package upgrade
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	_ "embed" // for the release keys
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// binaryName is the name of the binary in release archives
+const binaryName = "blessclient"
+
+//go:embed release_keys
+var releaseKeys []byte
+
+// TrustedKeys returns the keys we accept release signatures from:
+// the keys we ship with and any extra ones in authorized_keys format
+func TrustedKeys(extra []string) ([]ssh.PublicKey, error) {
+	keys, err := ParseTrustedKeys(releaseKeys)
+	if err != nil {
+		return nil, err
+	}
+	extraKeys, err := ParseTrustedKeys([]byte(strings.Join(extra, "\n")))
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, extraKeys...), nil
+}
+
+// Executable returns the path of the running binary, with symlinks resolved
+func Executable() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", errors.Wrap(err, "could not find the blessclient binary")
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not resolve %s", exe)
+	}
+	if strings.Contains(exe, "/Cellar/") {
+		return "", errors.Errorf("%s is managed by homebrew, run `brew upgrade blessclient` instead", exe)
+	}
+	return exe, nil
+}
+
+// Install downloads release, verifies it was signed by one of trustedKeys
+// and atomically replaces the binary at exe with it.
+// The archive must match the signed checksum of release.ArchiveName(), whatever its URL.
+func Install(ctx context.Context, release *Release, trustedKeys []ssh.PublicKey, exe string) error {
+	if len(trustedKeys) == 0 {
+		return errors.New("no trusted release keys, can't verify the release")
+	}
+	archiveName := release.ArchiveName()
+
+	dir, err := ioutil.TempDir("", "blessclient-upgrade")
+	if err != nil {
+		return errors.Wrap(err, "could not create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	checksumsPath := filepath.Join(dir, "checksums.txt")
+	signaturePath := filepath.Join(dir, "checksums.txt.sig")
+	archivePath := filepath.Join(dir, archiveName)
+	for src, dst := range map[string]string{
+		release.ChecksumsURL: checksumsPath,
+		release.SignatureURL: signaturePath,
+	} {
+		err = fetch(ctx, src, dst)
+		if err != nil {
+			return err
+		}
+	}
+
+	checksums, err := ioutil.ReadFile(checksumsPath)
+	if err != nil {
+		return errors.Wrapf(err, "could not read %s", checksumsPath)
+	}
+	signature, err := ioutil.ReadFile(signaturePath)
+	if err != nil {
+		return errors.Wrapf(err, "could not read %s", signaturePath)
+	}
+	err = VerifySignature(checksums, signature, trustedKeys)
+	if err != nil {
+		return errors.Wrapf(err, "could not verify the signature of release %s", release.Version)
+	}
+
+	err = fetch(ctx, release.ArchiveURL, archivePath)
+	if err != nil {
+		return err
+	}
+	err = VerifyChecksum(checksums, archiveName, archivePath)
+	if err != nil {
+		return err
+	}
+	return replace(archivePath, exe)
+}
+
+// replace extracts the binary in the archive at archivePath next to exe and renames it over exe
+func replace(archivePath string, exe string) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return errors.Wrapf(err, "could not stat %s", exe)
+	}
+
+	// same directory so the rename is atomic
+	tmp, err := ioutil.TempFile(filepath.Dir(exe), ".blessclient-upgrade")
+	if err != nil {
+		return errors.Wrapf(err, "could not write to %s", filepath.Dir(exe))
+	}
+	defer os.Remove(tmp.Name()) // nolint: errcheck
+
+	err = extract(archivePath, tmp)
+	closeErr := tmp.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return errors.Wrapf(closeErr, "could not write %s", tmp.Name())
+	}
+
+	err = os.Chmod(tmp.Name(), info.Mode().Perm())
+	if err != nil {
+		return errors.Wrapf(err, "could not chmod %s", tmp.Name())
+	}
+	return errors.Wrapf(os.Rename(tmp.Name(), exe), "could not replace %s", exe)
+}
+
+// extract copies the blessclient binary in the tar.gz at archivePath to w
+func extract(archivePath string, w io.Writer) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return errors.Wrapf(err, "could not open %s", archivePath)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return errors.Wrapf(err, "could not decompress %s", archivePath)
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return errors.Errorf("%s has no %s binary", archivePath, binaryName)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "could not read %s", archivePath)
+		}
+		if header.Typeflag != tar.TypeReg || filepath.Base(header.Name) != binaryName {
+			continue
+		}
+		_, err = io.Copy(w, archive) // #nosec the archive matched a signed checksum
+		return errors.Wrapf(err, "could not extract %s", binaryName)
+	}
+}
@@ -0,0 +1,142 @@
+package upgrade
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+var testArchiveName = fmt.Sprintf("blessclient_1.2.0_%s.tar.gz", Platform)
+
+func newArchive(t *testing.T, binary []byte) []byte {
+	r := require.New(t)
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	archive := tar.NewWriter(gz)
+	for name, content := range map[string][]byte{"README.md": []byte("readme"), binaryName: binary} {
+		r.NoError(archive.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0755,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := archive.Write(content)
+		r.NoError(err)
+	}
+	r.NoError(archive.Close())
+	r.NoError(gz.Close())
+	return buf.Bytes()
+}
+
+// serveRelease serves a release signed by signer, with checksums for archive unless they are overridden
+func serveRelease(t *testing.T, signer ssh.Signer, archive []byte, checksums []byte) (*httptest.Server, *Release) {
+	if checksums == nil {
+		checksums = []byte(fmt.Sprintf("%x  %s\n", sha256.Sum256(archive), testArchiveName))
+	}
+	files := map[string][]byte{
+		"/" + testArchiveName: archive,
+		"/checksums.txt":      checksums,
+		"/checksums.txt.sig":  sign(t, signer, SignatureNamespace, checksums),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		content, ok := files[req.URL.Path]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		w.Write(content) // nolint: errcheck
+	}))
+	return server, &Release{
+		Version:      semver.MustParse("1.2.0"),
+		ArchiveURL:   server.URL + "/" + testArchiveName,
+		ChecksumsURL: server.URL + "/checksums.txt",
+		SignatureURL: server.URL + "/checksums.txt.sig",
+	}
+}
+
+func newExecutable(t *testing.T) string {
+	exe := filepath.Join(t.TempDir(), binaryName)
+	require.NoError(t, ioutil.WriteFile(exe, []byte("old binary"), 0750))
+	return exe
+}
+
+func TestInstall(t *testing.T) {
+	r := require.New(t)
+	signer := newSigner(t)
+	server, release := serveRelease(t, signer, newArchive(t, []byte("new binary")), nil)
+	defer server.Close()
+	exe := newExecutable(t)
+
+	err := Install(context.Background(), release, []ssh.PublicKey{signer.PublicKey()}, exe)
+	r.NoError(err)
+
+	content, err := ioutil.ReadFile(exe)
+	r.NoError(err)
+	r.Equal("new binary", string(content))
+	info, err := os.Stat(exe)
+	r.NoError(err)
+	r.Equal(os.FileMode(0750), info.Mode().Perm())
+
+	// nothing left behind next to the binary
+	entries, err := ioutil.ReadDir(filepath.Dir(exe))
+	r.NoError(err)
+	r.Len(entries, 1)
+}
+
+func TestInstallRejects(t *testing.T) {
+	archive := newArchive(t, []byte("new binary"))
+	signer := newSigner(t)
+
+	cases := map[string]struct {
+		checksums   []byte
+		trustedKeys []ssh.PublicKey
+		err         string
+	}{
+		"untrusted signer": {
+			trustedKeys: []ssh.PublicKey{newSigner(t).PublicKey()},
+			err:         "untrusted key",
+		},
+		"no trusted keys": {
+			err: "no trusted release keys",
+		},
+		"checksums of another release": {
+			checksums:   []byte(fmt.Sprintf("%x  blessclient_1.1.0_%s.tar.gz\n", sha256.Sum256(archive), Platform)),
+			trustedKeys: []ssh.PublicKey{signer.PublicKey()},
+			err:         "no checksum for " + testArchiveName,
+		},
+		"checksum mismatch": {
+			checksums:   []byte(fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte("other")), testArchiveName)),
+			trustedKeys: []ssh.PublicKey{signer.PublicKey()},
+			err:         "checksum mismatch",
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := require.New(t)
+			server, release := serveRelease(t, signer, archive, c.checksums)
+			defer server.Close()
+			exe := newExecutable(t)
+
+			err := Install(context.Background(), release, c.trustedKeys, exe)
+			r.Error(err)
+			r.Contains(err.Error(), c.err)
+
+			content, err := ioutil.ReadFile(exe)
+			r.NoError(err)
+			r.Equal("old binary", string(content))
+		})
+	}
+}
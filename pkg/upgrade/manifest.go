@@ -0,0 +1,102 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/blang/semver"
+	getter "github.com/hashicorp/go-getter"
+	"github.com/pkg/errors"
+)
+
+// Manifest describes a release hosted outside GitHub. The checksums must list
+// each archive under the name goreleaser gives it, see Release.ArchiveName. eg
+//
+//	{
+//	  "version": "1.2.0",
+//	  "checksums": "https://example.com/blessclient/1.2.0/checksums.txt",
+//	  "signature": "https://example.com/blessclient/1.2.0/checksums.txt.sig",
+//	  "archives": {
+//	    "linux_amd64": "https://example.com/blessclient/1.2.0/blessclient_1.2.0_linux_amd64.tar.gz"
+//	  }
+//	}
+type Manifest struct {
+	Version   string `json:"version"`
+	Checksums string `json:"checksums"`
+	Signature string `json:"signature"`
+	// Archives maps a platform to its archive
+	Archives map[string]string `json:"archives"`
+}
+
+// ManifestSource reads the latest release from a manifest
+type ManifestSource struct {
+	url string
+}
+
+// NewManifestSource returns a source for the manifest at url, anything go-getter can fetch
+func NewManifestSource(url string) *ManifestSource {
+	return &ManifestSource{url: url}
+}
+
+// Latest returns the release in the manifest
+func (m *ManifestSource) Latest(ctx context.Context) (*Release, error) {
+	dir, err := ioutil.TempDir("", "blessclient-manifest")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+
+	manifestPath := filepath.Join(dir, "manifest.json")
+	err = fetch(ctx, m.url, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(manifestPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read %s", manifestPath)
+	}
+
+	manifest := &Manifest{}
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not decode manifest at %s", m.url)
+	}
+	return manifest.release()
+}
+
+// release picks the archive for this platform
+func (m *Manifest) release() (*Release, error) {
+	version, err := semver.ParseTolerant(m.Version)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse manifest version %s", m.Version)
+	}
+	archive, ok := m.Archives[Platform]
+	if !ok {
+		return nil, errors.Errorf("release %s has no archive for %s", m.Version, Platform)
+	}
+	if m.Checksums == "" || m.Signature == "" {
+		return nil, errors.Errorf("release %s is not signed", m.Version)
+	}
+	return &Release{
+		Version:      version,
+		ArchiveURL:   archive,
+		ChecksumsURL: m.Checksums,
+		SignatureURL: m.Signature,
+	}, nil
+}
+
+// fetch downloads src to the file dst as is
+func fetch(ctx context.Context, src string, dst string) error {
+	client := &getter.Client{
+		Ctx:  ctx,
+		Src:  src,
+		Dst:  dst,
+		Mode: getter.ClientModeFile,
+		// we verify archives before we unpack them ourselves
+		Decompressors: map[string]getter.Decompressor{},
+	}
+	return errors.Wrapf(client.Get(), "could not fetch %s", src)
+}
@@ -0,0 +1,43 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestSourceLatest(t *testing.T) {
+	r := require.New(t)
+	manifestPath := filepath.Join(t.TempDir(), "manifest.json")
+	r.NoError(ioutil.WriteFile(manifestPath, []byte(fmt.Sprintf(`{
+		"version": "1.3.0",
+		"checksums": "https://example.com/checksums.txt",
+		"signature": "https://example.com/checksums.txt.sig",
+		"archives": {"%s": "https://example.com/blessclient_1.3.0.tar.gz"}
+	}`, Platform)), 0644))
+
+	release, err := NewManifestSource(manifestPath).Latest(context.Background())
+	r.NoError(err)
+	r.Equal("1.3.0", release.Version.String())
+	r.Equal("https://example.com/blessclient_1.3.0.tar.gz", release.ArchiveURL)
+	r.Equal("https://example.com/checksums.txt", release.ChecksumsURL)
+	r.Equal("https://example.com/checksums.txt.sig", release.SignatureURL)
+	r.Equal(fmt.Sprintf("blessclient_1.3.0_%s.tar.gz", Platform), release.ArchiveName())
+}
+
+func TestManifestNoArchive(t *testing.T) {
+	r := require.New(t)
+	manifest := &Manifest{
+		Version:   "1.3.0",
+		Checksums: "https://example.com/checksums.txt",
+		Signature: "https://example.com/checksums.txt.sig",
+		Archives:  map[string]string{"plan9_386": "https://example.com/plan9.tar.gz"},
+	}
+	_, err := manifest.release()
+	r.Error(err)
+	r.Contains(err.Error(), "has no archive for "+Platform)
+}
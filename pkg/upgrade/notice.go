@@ -0,0 +1,94 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/pkg/errors"
+)
+
+// NoticeInterval is how often we look for a newer release
+const NoticeInterval = 24 * time.Hour
+
+// noticeCache remembers the latest release we found
+type noticeCache struct {
+	// CacheKey is util.VersionCacheKey of the binary that checked,
+	// so we check again right after an upgrade
+	CacheKey  string    `json:"cache_key"`
+	CheckedAt time.Time `json:"checked_at"`
+	// Latest is empty if we could not find out
+	Latest string `json:"latest,omitempty"`
+}
+
+// Notice returns a message if there is a release newer than this binary, empty otherwise.
+// It asks source at most once per NoticeInterval and remembers the answer in cachePath.
+func Notice(ctx context.Context, source Source, cachePath string, now time.Time) (string, error) {
+	cacheKey := util.VersionCacheKey()
+	if cacheKey == "" {
+		// not a version we can compare
+		return "", nil
+	}
+	current, err := CurrentVersion()
+	if err != nil {
+		return "", err
+	}
+
+	cache := readNoticeCache(cachePath)
+	if cache == nil || cache.CacheKey != cacheKey || now.Sub(cache.CheckedAt) >= NoticeInterval {
+		cache = &noticeCache{CacheKey: cacheKey, CheckedAt: now}
+		release, err := source.Latest(ctx)
+		// remember failures too so we don't slow down every run
+		if err == nil {
+			cache.Latest = release.Version.String()
+		}
+		writeErr := writeNoticeCache(cachePath, cache)
+		if err != nil {
+			return "", err
+		}
+		if writeErr != nil {
+			return "", writeErr
+		}
+	}
+
+	if cache.Latest == "" {
+		return "", nil
+	}
+	latest, err := semver.Parse(cache.Latest)
+	if err != nil || !latest.GT(current) {
+		return "", nil
+	}
+	return fmt.Sprintf("blessclient %s is available, you have %s. Run `blessclient upgrade` to install it", latest, current), nil
+}
+
+// readNoticeCache returns nil if there is no usable cache at cachePath
+func readNoticeCache(cachePath string) *noticeCache {
+	data, err := ioutil.ReadFile(cachePath)
+	if err != nil {
+		return nil
+	}
+	cache := &noticeCache{}
+	err = json.Unmarshal(data, cache)
+	if err != nil {
+		return nil
+	}
+	return cache
+}
+
+func writeNoticeCache(cachePath string, cache *noticeCache) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal upgrade check")
+	}
+	err = os.MkdirAll(filepath.Dir(cachePath), 0755) // #nosec
+	if err != nil {
+		return errors.Wrapf(err, "could not create %s", filepath.Dir(cachePath))
+	}
+	return errors.Wrapf(ioutil.WriteFile(cachePath, data, 0644), "could not write %s", cachePath)
+}
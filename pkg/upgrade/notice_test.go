@@ -0,0 +1,93 @@
+package upgrade
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// setVersion pretends this is a release build of version
+func setVersion(t *testing.T, version string) {
+	oldVersion, oldRelease := util.Version, util.Release
+	util.Version, util.Release = version, "true"
+	t.Cleanup(func() {
+		util.Version, util.Release = oldVersion, oldRelease
+	})
+}
+
+type fakeSource struct {
+	calls   int
+	release *Release
+	err     error
+}
+
+func (f *fakeSource) Latest(ctx context.Context) (*Release, error) {
+	f.calls++
+	return f.release, f.err
+}
+
+func TestNotice(t *testing.T) {
+	r := require.New(t)
+	setVersion(t, "1.1.0")
+	cachePath := filepath.Join(t.TempDir(), "upgrade_check.json")
+	source := &fakeSource{release: &Release{Version: semver.MustParse("1.2.0")}}
+	now := time.Date(2030, 7, 20, 12, 0, 0, 0, time.UTC)
+
+	notice, err := Notice(context.Background(), source, cachePath, now)
+	r.NoError(err)
+	r.Contains(notice, "blessclient 1.2.0 is available, you have 1.1.0")
+	r.Equal(1, source.calls)
+
+	// cached
+	notice, err = Notice(context.Background(), source, cachePath, now.Add(time.Hour))
+	r.NoError(err)
+	r.Contains(notice, "1.2.0 is available")
+	r.Equal(1, source.calls)
+
+	// the cache expired
+	_, err = Notice(context.Background(), source, cachePath, now.Add(NoticeInterval))
+	r.NoError(err)
+	r.Equal(2, source.calls)
+
+	// we upgraded since the last check
+	setVersion(t, "1.2.0")
+	notice, err = Notice(context.Background(), source, cachePath, now.Add(NoticeInterval))
+	r.NoError(err)
+	r.Empty(notice)
+	r.Equal(3, source.calls)
+}
+
+func TestNoticeRemembersFailures(t *testing.T) {
+	r := require.New(t)
+	setVersion(t, "1.1.0")
+	cachePath := filepath.Join(t.TempDir(), "upgrade_check.json")
+	source := &fakeSource{err: errors.New("rate limited")}
+	now := time.Date(2030, 7, 20, 12, 0, 0, 0, time.UTC)
+
+	_, err := Notice(context.Background(), source, cachePath, now)
+	r.Error(err)
+
+	notice, err := Notice(context.Background(), source, cachePath, now.Add(time.Minute))
+	r.NoError(err)
+	r.Empty(notice)
+	r.Equal(1, source.calls)
+}
+
+func TestNoticeDevBuild(t *testing.T) {
+	r := require.New(t)
+	source := &fakeSource{}
+	oldVersion := util.Version
+	util.Version = "undefined"
+	defer func() { util.Version = oldVersion }()
+
+	notice, err := Notice(context.Background(), source, filepath.Join(t.TempDir(), "upgrade_check.json"), time.Now())
+	r.NoError(err)
+	r.Empty(notice)
+	r.Equal(0, source.calls)
+}
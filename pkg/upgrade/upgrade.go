@@ -0,0 +1,65 @@
+// Package upgrade finds newer blessclient releases and installs them in place of
+// the running binary, after checking they were signed by a trusted release key.
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/blang/semver"
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/pkg/errors"
+)
+
+// Platform is the os and arch of this binary as goreleaser names them, eg linux_amd64
+var Platform = runtime.GOOS + "_" + runtime.GOARCH
+
+// Release is a published blessclient version
+type Release struct {
+	Version semver.Version
+	// ArchiveURL is the archive with the binary for this platform
+	ArchiveURL string
+	// ChecksumsURL lists the sha256 of every archive in the release
+	ChecksumsURL string
+	// SignatureURL is an ssh signature of the checksums
+	SignatureURL string
+}
+
+// ArchiveName returns the name goreleaser gives the archive for this platform.
+// We only trust the checksum listed under this name, so signed checksums
+// of an older release can't be passed off as this one.
+func (r *Release) ArchiveName() string {
+	return fmt.Sprintf("blessclient_%s_%s.tar.gz", r.Version, Platform)
+}
+
+// Source finds the latest release
+type Source interface {
+	Latest(ctx context.Context) (*Release, error)
+}
+
+// NewSource returns the manifest at manifestURL, or GitHub releases if it is empty
+func NewSource(manifestURL string) Source {
+	if manifestURL == "" {
+		return NewGitHubSource(DefaultGitHubRepo)
+	}
+	return NewManifestSource(manifestURL)
+}
+
+// CurrentVersion returns the version of this binary
+func CurrentVersion() (semver.Version, error) {
+	versionString, err := util.VersionString()
+	if err != nil {
+		return semver.Version{}, err
+	}
+	v, err := semver.Parse(versionString)
+	if err != nil {
+		return semver.Version{}, errors.Wrapf(err, "could not parse version %s", versionString)
+	}
+	return v, nil
+}
+
+// IsNewer returns true if release is newer than current
+func IsNewer(release *Release, current semver.Version) bool {
+	return release.Version.GT(current)
+}
@@ -0,0 +1,161 @@
+package upgrade
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/pem"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+// SignatureNamespace is the namespace releases are signed in, see ssh-keygen -Y sign
+const SignatureNamespace = "blessclient-release"
+
+// ParseTrustedKeys parses ssh public keys in authorized_keys format, one per line
+func ParseTrustedKeys(data []byte) ([]ssh.PublicKey, error) {
+	keys := []ssh.PublicKey{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(line))
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not parse trusted key %q", line)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// sshSignature is the blob in an armored ssh signature, see PROTOCOL.sshsig in openssh
+type sshSignature struct {
+	MagicPreamble [6]byte
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Signature     []byte
+}
+
+// sshSignedData is what the key actually signed
+type sshSignedData struct {
+	MagicPreamble [6]byte
+	Namespace     string
+	Reserved      string
+	HashAlgorithm string
+	Hash          []byte
+}
+
+var sshSignatureMagic = [6]byte{'S', 'S', 'H', 'S', 'I', 'G'}
+
+// VerifySignature checks that armored is an ssh signature of message
+// in SignatureNamespace by one of trustedKeys
+func VerifySignature(message []byte, armored []byte, trustedKeys []ssh.PublicKey) error {
+	block, _ := pem.Decode(armored)
+	if block == nil || block.Type != "SSH SIGNATURE" {
+		return errors.New("signature is not an armored ssh signature")
+	}
+
+	sig := &sshSignature{}
+	err := ssh.Unmarshal(block.Bytes, sig)
+	if err != nil {
+		return errors.Wrap(err, "could not parse ssh signature")
+	}
+	if sig.MagicPreamble != sshSignatureMagic || sig.Version != 1 {
+		return errors.New("unsupported ssh signature")
+	}
+	if sig.Namespace != SignatureNamespace {
+		return errors.Errorf("signature is for %q, not %q", sig.Namespace, SignatureNamespace)
+	}
+
+	publicKey, err := ssh.ParsePublicKey(sig.PublicKey)
+	if err != nil {
+		return errors.Wrap(err, "could not parse the signing key")
+	}
+	if !isTrusted(publicKey, trustedKeys) {
+		return errors.Errorf("signed by untrusted key %s", ssh.FingerprintSHA256(publicKey))
+	}
+
+	var h hash.Hash
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return errors.Errorf("unsupported signature hash %s", sig.HashAlgorithm)
+	}
+	h.Write(message) // nolint: errcheck
+
+	signature := &ssh.Signature{}
+	err = ssh.Unmarshal(sig.Signature, signature)
+	if err != nil {
+		return errors.Wrap(err, "could not parse signature")
+	}
+	signed := ssh.Marshal(&sshSignedData{
+		MagicPreamble: sshSignatureMagic,
+		Namespace:     sig.Namespace,
+		Reserved:      sig.Reserved,
+		HashAlgorithm: sig.HashAlgorithm,
+		Hash:          h.Sum(nil),
+	})
+	return errors.Wrap(publicKey.Verify(signed, signature), "bad signature")
+}
+
+func isTrusted(key ssh.PublicKey, trustedKeys []ssh.PublicKey) bool {
+	marshaled := key.Marshal()
+	for _, trusted := range trustedKeys {
+		if bytes.Equal(marshaled, trusted.Marshal()) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyChecksum checks the sha256 of the file at filePath against its entry
+// for name in checksums, in sha256sum format
+func VerifyChecksum(checksums []byte, name string, filePath string) error {
+	expected := ""
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == name {
+			expected = fields[0]
+			break
+		}
+	}
+	if expected == "" {
+		return errors.Errorf("no checksum for %s", name)
+	}
+	expectedSum, err := hex.DecodeString(expected)
+	if err != nil {
+		return errors.Wrapf(err, "could not decode checksum for %s", name)
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return errors.Wrapf(err, "could not open %s", filePath)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, f)
+	if err != nil {
+		return errors.Wrapf(err, "could not read %s", filePath)
+	}
+	if subtle.ConstantTimeCompare(h.Sum(nil), expectedSum) != 1 {
+		return errors.Errorf("checksum mismatch for %s", name)
+	}
+	return nil
+}
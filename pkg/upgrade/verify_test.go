@@ -0,0 +1,123 @@
+package upgrade
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func newSigner(t *testing.T) ssh.Signer {
+	r := require.New(t)
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	r.NoError(err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	r.NoError(err)
+	return signer
+}
+
+// sign does what ssh-keygen -Y sign -n namespace does
+func sign(t *testing.T, signer ssh.Signer, namespace string, message []byte) []byte {
+	r := require.New(t)
+	h := sha512.Sum512(message)
+	signature, err := signer.Sign(rand.Reader, ssh.Marshal(&sshSignedData{
+		MagicPreamble: sshSignatureMagic,
+		Namespace:     namespace,
+		HashAlgorithm: "sha512",
+		Hash:          h[:],
+	}))
+	r.NoError(err)
+
+	blob := ssh.Marshal(&sshSignature{
+		MagicPreamble: sshSignatureMagic,
+		Version:       1,
+		PublicKey:     signer.PublicKey().Marshal(),
+		Namespace:     namespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(signature),
+	})
+	return pem.EncodeToMemory(&pem.Block{Type: "SSH SIGNATURE", Bytes: blob})
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return data
+}
+
+func TestVerifySignatureSSHKeygen(t *testing.T) {
+	r := require.New(t)
+	checksums := readTestdata(t, "checksums.txt")
+	signature := readTestdata(t, "checksums.txt.sig")
+	trustedKeys, err := ParseTrustedKeys(readTestdata(t, "release_key.pub"))
+	r.NoError(err)
+	r.Len(trustedKeys, 1)
+
+	r.NoError(VerifySignature(checksums, signature, trustedKeys))
+
+	err = VerifySignature(append(checksums, '\n'), signature, trustedKeys)
+	r.Error(err)
+	r.Contains(err.Error(), "bad signature")
+
+	err = VerifySignature(checksums, signature, []ssh.PublicKey{newSigner(t).PublicKey()})
+	r.Error(err)
+	r.Contains(err.Error(), "untrusted key")
+
+	err = VerifySignature(checksums, checksums, trustedKeys)
+	r.Error(err)
+	r.Contains(err.Error(), "not an armored ssh signature")
+}
+
+func TestVerifySignatureNamespace(t *testing.T) {
+	r := require.New(t)
+	signer := newSigner(t)
+	trustedKeys := []ssh.PublicKey{signer.PublicKey()}
+	message := []byte("checksums")
+
+	r.NoError(VerifySignature(message, sign(t, signer, SignatureNamespace, message), trustedKeys))
+
+	// a signature made for something else
+	err := VerifySignature(message, sign(t, signer, "file", message), trustedKeys)
+	r.Error(err)
+	r.Contains(err.Error(), `signature is for "file"`)
+}
+
+func TestParseTrustedKeys(t *testing.T) {
+	r := require.New(t)
+
+	keys, err := ParseTrustedKeys([]byte("# a comment\n\n" + string(readTestdata(t, "release_key.pub"))))
+	r.NoError(err)
+	r.Len(keys, 1)
+
+	_, err = ParseTrustedKeys([]byte("ssh-ed25519 not-base64"))
+	r.Error(err)
+
+	// the keys we ship with must parse
+	_, err = TrustedKeys(nil)
+	r.NoError(err)
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	r := require.New(t)
+	filePath := filepath.Join(t.TempDir(), "archive.tar.gz")
+	r.NoError(ioutil.WriteFile(filePath, []byte("hello\n"), 0644))
+
+	checksums := []byte(
+		"0000000000000000000000000000000000000000000000000000000000000000  other.tar.gz\n" +
+			"5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  archive.tar.gz\n")
+	r.NoError(VerifyChecksum(checksums, "archive.tar.gz", filePath))
+
+	err := VerifyChecksum(checksums, "other.tar.gz", filePath)
+	r.Error(err)
+	r.Contains(err.Error(), "checksum mismatch")
+
+	err = VerifyChecksum(checksums, "missing.tar.gz", filePath)
+	r.Error(err)
+	r.Contains(err.Error(), "no checksum for missing.tar.gz")
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// WriteFileAtomic writes data to filePath through a temporary file and a rename,
+// so concurrent readers see either the old or the new content
+func WriteFileAtomic(filePath string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(path.Dir(filePath), path.Base(filePath))
+	if err != nil {
+		return errors.Wrapf(err, "could not create temporary file for %s", filePath)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		return errors.Wrapf(err, "could not write %s", tmp.Name())
+	}
+	err = tmp.Chmod(perm)
+	if err != nil {
+		return errors.Wrapf(err, "could not chmod %s", tmp.Name())
+	}
+	err = tmp.Close()
+	if err != nil {
+		return errors.Wrapf(err, "could not close %s", tmp.Name())
+	}
+	return errors.Wrapf(os.Rename(tmp.Name(), filePath), "could not write %s", filePath)
+}
@@ -0,0 +1,32 @@
+package util_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/chanzuckerberg/blessclient/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	r := require.New(t)
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "health.json")
+
+	r.NoError(util.WriteFileAtomic(filePath, []byte("first"), 0600))
+	r.NoError(util.WriteFileAtomic(filePath, []byte("second"), 0600))
+
+	data, err := ioutil.ReadFile(filePath)
+	r.NoError(err)
+	r.Equal("second", string(data))
+	info, err := os.Stat(filePath)
+	r.NoError(err)
+	r.Equal(os.FileMode(0600), info.Mode().Perm())
+
+	// no temporary files left behind
+	entries, err := ioutil.ReadDir(dir)
+	r.NoError(err)
+	r.Len(entries, 1)
+}
@@ -0,0 +1,24 @@
+//go:build !unix
+
+package util
+
+// flock is a no-op where there is no flock(2). Exclusive holders are still
+// serialized by the pid lockfile, but shared holders don't wait for them.
+type flock struct {
+	path string
+}
+
+// tryLockShared always succeeds
+func (f *flock) tryLockShared() error {
+	return nil
+}
+
+// tryLockExclusive always succeeds
+func (f *flock) tryLockExclusive() error {
+	return nil
+}
+
+// unlock does nothing
+func (f *flock) unlock() error {
+	return nil
+}
@@ -0,0 +1,55 @@
+//go:build unix
+
+package util
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// flock is an advisory lock on a file.
+// The kernel releases it when we exit, so it can't go stale.
+type flock struct {
+	path string
+	file *os.File
+}
+
+// tryLockShared takes a shared flock without blocking
+func (f *flock) tryLockShared() error {
+	return f.tryLock(syscall.LOCK_SH)
+}
+
+// tryLockExclusive takes an exclusive flock without blocking
+func (f *flock) tryLockExclusive() error {
+	return f.tryLock(syscall.LOCK_EX)
+}
+
+// tryLock takes the flock without blocking, how is syscall.LOCK_SH or syscall.LOCK_EX
+func (f *flock) tryLock(how int) error {
+	if f.file == nil {
+		file, err := os.OpenFile(f.path, os.O_CREATE|os.O_RDWR, 0600) // #nosec
+		if err != nil {
+			return errors.Wrapf(err, "could not open %s", f.path)
+		}
+		f.file = file
+	}
+
+	err := syscall.Flock(int(f.file.Fd()), how|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return errFlockBusy
+	}
+	return errors.Wrapf(err, "could not flock %s", f.path)
+}
+
+// unlock releases the flock if we have it
+func (f *flock) unlock() error {
+	if f.file == nil {
+		return nil
+	}
+	// closing the file releases the flock
+	err := f.file.Close()
+	f.file = nil
+	return errors.Wrapf(err, "could not close %s", f.path)
+}
@@ -1,8 +1,12 @@
 package util
 
 import (
+	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cenkalti/backoff"
@@ -20,18 +24,81 @@ func lockPath(configPath string) (string, error) {
 	return path.Join(configDir, ".lock"), nil
 }
 
-func defaultBackoff() backoff.BackOff {
+// LockMode is how a command takes the lock
+type LockMode string
+
+// Lock modes
+const (
+	// LockNone never waits
+	LockNone LockMode = "none"
+	// LockShared runs alongside other shared holders but never with an exclusive one
+	LockShared LockMode = "shared"
+	// LockExclusive runs alone
+	LockExclusive LockMode = "exclusive"
+)
+
+// errFlockBusy means someone else holds a conflicting flock
+var errFlockBusy = errors.New("flock is busy")
+
+// DefaultLockWait is how long we wait for another process to release the lock
+const DefaultLockWait = 20 * time.Second
+
+func waitBackoff(wait time.Duration) backoff.BackOff {
+	if wait <= 0 {
+		return &backoff.StopBackOff{}
+	}
 	b := backoff.NewExponentialBackOff()
-	b.MaxElapsedTime = 20 * time.Second
+	b.MaxElapsedTime = wait
 	b.InitialInterval = 10 * time.Millisecond
 	b.MaxInterval = 100 * time.Millisecond
 	return b
 }
 
-// Lock represents a pid lock
+// Holder describes the process holding a lock
+type Holder struct {
+	PID int
+	// Cmdline is the holder's command line, empty if we can't tell
+	Cmdline string
+	// Age is how long the lock has been held
+	Age time.Duration
+	// Stale is true if the holder is dead or the lockfile has no valid pid
+	Stale bool
+}
+
+// String returns a human readable description of the holder
+func (h *Holder) String() string {
+	cmdline := h.Cmdline
+	if cmdline == "" {
+		cmdline = "unknown command"
+	}
+	return fmt.Sprintf("pid %d (%s) for %s", h.PID, cmdline, h.Age.Round(time.Second))
+}
+
+// LockedError means another process held the lock for longer than we were willing to wait
+type LockedError struct {
+	Path   string
+	Holder *Holder
+}
+
+// Error returns the string representation of this error
+func (e *LockedError) Error() string {
+	return fmt.Sprintf(
+		"Error acquiring lock at %s: held by %s. Wait for it to finish or stop it",
+		e.Path, e.Holder)
+}
+
+// Lock represents a pid lock.
+// Exclusive holders write their pid so others can tell who they are waiting on,
+// and everyone flocks a file next to it to tell shared and exclusive holders apart.
 type Lock struct {
 	lock    lockfile.Lockfile
+	flock   *flock
+	mode    LockMode
 	backoff backoff.BackOff
+	// pidLocked is true if we hold the pid lockfile
+	pidLocked bool
+	// waited is true if we had to wait for another process
+	waited bool
 }
 
 // NewLock returns a new lock
@@ -55,21 +122,199 @@ func NewLock(configPath string) (*Lock, error) {
 
 	return &Lock{
 		lock:    lock,
-		backoff: defaultBackoff(),
+		flock:   &flock{path: lockPath + ".flock"},
+		mode:    LockExclusive,
+		backoff: waitBackoff(DefaultLockWait),
 	}, nil
 }
 
-// Lock will lock with retries.
+// SetMode sets how Lock locks, LockExclusive by default
+func (l *Lock) SetMode(mode LockMode) {
+	l.mode = mode
+}
+
+// Mode returns how Lock locks
+func (l *Lock) Mode() LockMode {
+	return l.mode
+}
+
+// SetWait sets how long Lock waits for another process to release the lock.
+// Zero means we try once.
+func (l *Lock) SetWait(wait time.Duration) {
+	l.backoff = waitBackoff(wait)
+}
+
+// Lock will lock with retries, removing stale locks left behind by dead processes.
 func (l *Lock) Lock(optBackoff ...backoff.BackOff) error {
+	if l.mode == LockNone {
+		return nil
+	}
+
 	b := l.backoff
 	if len(optBackoff) == 1 {
 		b = optBackoff[0]
 	}
 
-	return errors.Wrap(backoff.Retry(l.lock.TryLock, b), "Error acquiring lock at ~/.blessclient/.lock")
+	l.waited = false
+	notify := func(err error, next time.Duration) {
+		if l.waited {
+			return
+		}
+		l.waited = true
+		holder := l.otherHolder()
+		if holder != nil {
+			logrus.Infof("Waiting for %s to release the lock", holder)
+		} else {
+			logrus.Info("Waiting for other blessclient commands to release the lock")
+		}
+	}
+
+	err := backoff.RetryNotify(l.tryLock, b, notify)
+	if err == nil {
+		return nil
+	}
+	holder := l.otherHolder()
+	if l.pidLocked {
+		unlockErr := l.lock.Unlock()
+		if unlockErr != nil {
+			logrus.WithError(unlockErr).Debug("could not release the pid lock")
+		}
+		l.pidLocked = false
+	}
+	if holder != nil {
+		return &LockedError{Path: l.Path(), Holder: holder}
+	}
+	if err == errFlockBusy {
+		return errors.Errorf("Error acquiring lock at %s: other blessclient commands are still using it", l.Path())
+	}
+	return errors.Wrapf(err, "Error acquiring lock at %s", l.Path())
+}
+
+// otherHolder returns the process holding the lock unless it is us
+func (l *Lock) otherHolder() *Holder {
+	holder, err := l.Holder()
+	if err != nil || holder == nil || holder.PID == os.Getpid() {
+		return nil
+	}
+	return holder
+}
+
+// tryLock tries to take the lock once.
+// Exclusive holders take the pid lockfile first so waiters can tell who they are waiting on.
+func (l *Lock) tryLock() error {
+	if l.mode == LockShared {
+		return l.flock.tryLockShared()
+	}
+
+	if !l.pidLocked {
+		err := l.tryPidLock()
+		if err != nil {
+			return err
+		}
+		l.pidLocked = true
+	}
+	return l.flock.tryLockExclusive()
+}
+
+// tryPidLock tries to take the pid lockfile once. lockfile already removes locks of dead
+// processes, we retry once if the holder died while we were looking.
+func (l *Lock) tryPidLock() error {
+	err := l.lock.TryLock()
+	if err != lockfile.ErrBusy {
+		return err
+	}
+
+	holder, holderErr := l.Holder()
+	if holderErr != nil || holder == nil || !holder.Stale {
+		return err
+	}
+	logrus.Infof("Removing stale lock at %s held by %s", l.Path(), holder)
+	err = l.removeStale(holder)
+	if err != nil {
+		return err
+	}
+	return l.lock.TryLock()
+}
+
+// removeStale removes the lockfile if it is still held by the stale holder,
+// so we never remove a lock another process took in the meantime
+func (l *Lock) removeStale(stale *Holder) error {
+	holder, err := l.Holder()
+	if err != nil {
+		return err
+	}
+	if holder == nil {
+		return nil
+	}
+	if !holder.Stale || holder.PID != stale.PID {
+		return lockfile.ErrBusy
+	}
+	err = os.Remove(l.Path())
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "could not remove stale lock at %s", l.Path())
+	}
+	return nil
+}
+
+// Waited returns true if the last Lock had to wait for another process
+func (l *Lock) Waited() bool {
+	return l.waited
+}
+
+// Path returns the path of the lockfile
+func (l *Lock) Path() string {
+	return string(l.lock)
+}
+
+// Holder returns the process holding the lock, nil if nobody holds it
+func (l *Lock) Holder() (*Holder, error) {
+	info, err := os.Stat(l.Path())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not stat %s", l.Path())
+	}
+	data, err := ioutil.ReadFile(l.Path())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read %s", l.Path())
+	}
+
+	holder := &Holder{Age: time.Since(info.ModTime())}
+	holder.PID, err = strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		// junk, nobody can be holding it
+		holder.Stale = true
+		return holder, nil
+	}
+
+	_, err = l.lock.GetOwner()
+	switch err {
+	case nil:
+		holder.Cmdline = processCmdline(holder.PID)
+	case lockfile.ErrDeadOwner, lockfile.ErrInvalidPid:
+		holder.Stale = true
+	default:
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "could not find the owner of %s", l.Path())
+	}
+	return holder, nil
 }
 
 // Unlock will unlock the pid lockfile
 func (l *Lock) Unlock() error {
+	err := l.flock.unlock()
+	if err != nil {
+		return errors.Wrap(err, "Error releasing lock")
+	}
+	if l.mode != LockExclusive {
+		return nil
+	}
+	l.pidLocked = false
 	return errors.Wrap(l.lock.Unlock(), "Error releasing lock")
 }
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nightlyone/lockfile"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveStaleKeepsNewLock(t *testing.T) {
+	r := require.New(t)
+	dir, err := ioutil.TempDir("", "blessclient-lock-test")
+	r.NoError(err)
+	defer os.RemoveAll(dir)
+
+	l, err := NewLock(filepath.Join(dir, "config.yml"))
+	r.NoError(err)
+	r.NoError(ioutil.WriteFile(l.Path(), []byte("999999999\n"), 0644))
+	stale, err := l.Holder()
+	r.NoError(err)
+	r.True(stale.Stale)
+
+	// someone else took the lock after we looked
+	r.NoError(ioutil.WriteFile(l.Path(), []byte(fmt.Sprintf("%d\n", os.Getppid())), 0644))
+	r.Equal(lockfile.ErrBusy, l.removeStale(stale))
+	_, err = os.Stat(l.Path())
+	r.NoError(err)
+
+	// still the dead holder
+	r.NoError(ioutil.WriteFile(l.Path(), []byte("999999999\n"), 0644))
+	r.NoError(l.removeStale(stale))
+	_, err = os.Stat(l.Path())
+	r.True(os.IsNotExist(err))
+}
@@ -5,10 +5,12 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/cenkalti/backoff"
+	"github.com/pkg/errors"
 
 	"github.com/chanzuckerberg/blessclient/pkg/util"
 	"github.com/stretchr/testify/require"
@@ -50,6 +52,108 @@ func (ts *LockTestSuite) TestNewLockRelativePath() {
 	r.Nil(l)
 }
 
+func (ts *LockTestSuite) TestHolderUnlocked() {
+	r := require.New(ts.T())
+	l, err := util.NewLock(filepath.Join(ts.lockDir, "config.yml"))
+	r.Nil(err)
+
+	holder, err := l.Holder()
+	r.Nil(err)
+	r.Nil(holder)
+}
+
+func (ts *LockTestSuite) TestHolderSelf() {
+	r := require.New(ts.T())
+	l, err := util.NewLock(filepath.Join(ts.lockDir, "config.yml"))
+	r.Nil(err)
+	r.Nil(l.Lock())
+	// nolint: errcheck
+	defer l.Unlock()
+	r.False(l.Waited())
+
+	holder, err := l.Holder()
+	r.Nil(err)
+	r.NotNil(holder)
+	r.Equal(os.Getpid(), holder.PID)
+	r.False(holder.Stale)
+}
+
+func (ts *LockTestSuite) TestLockRemovesStaleLock() {
+	cases := map[string]string{
+		"junk":     "not a pid\n",
+		"dead pid": "999999999\n",
+	}
+	for name, content := range cases {
+		ts.Run(name, func() {
+			r := require.New(ts.T())
+			l, err := util.NewLock(filepath.Join(ts.lockDir, "config.yml"))
+			r.Nil(err)
+			r.Nil(ioutil.WriteFile(l.Path(), []byte(content), 0644))
+
+			holder, err := l.Holder()
+			r.Nil(err)
+			r.NotNil(holder)
+			r.True(holder.Stale)
+
+			l.SetWait(0)
+			r.Nil(l.Lock())
+			r.Nil(l.Unlock())
+		})
+	}
+}
+
+func (ts *LockTestSuite) TestLockPath() {
+	r := require.New(ts.T())
+	l, err := util.NewLock(filepath.Join(ts.lockDir, "config.yml"))
+	r.Nil(err)
+	r.Equal(filepath.Join(ts.lockDir, ".lock"), l.Path())
+}
+
+func (ts *LockTestSuite) newLock(mode util.LockMode) *util.Lock {
+	r := require.New(ts.T())
+	l, err := util.NewLock(filepath.Join(ts.lockDir, "config.yml"))
+	r.Nil(err)
+	l.SetMode(mode)
+	l.SetWait(0)
+	return l
+}
+
+func (ts *LockTestSuite) TestLockShared() {
+	r := require.New(ts.T())
+
+	first := ts.newLock(util.LockShared)
+	second := ts.newLock(util.LockShared)
+	r.Nil(first.Lock())
+	r.Nil(second.Lock())
+
+	exclusive := ts.newLock(util.LockExclusive)
+	err := exclusive.Lock()
+	r.NotNil(err)
+	r.Contains(err.Error(), "other blessclient commands are still using it")
+
+	r.Nil(first.Unlock())
+	r.Nil(second.Unlock())
+	r.Nil(exclusive.Lock())
+	r.Nil(exclusive.Unlock())
+}
+
+func (ts *LockTestSuite) TestLockSharedWaitsForExclusive() {
+	r := require.New(ts.T())
+
+	exclusive := ts.newLock(util.LockExclusive)
+	r.Nil(exclusive.Lock())
+
+	shared := ts.newLock(util.LockShared)
+	r.NotNil(shared.Lock())
+
+	// commands that don't lock never wait
+	r.Nil(ts.newLock(util.LockNone).Lock())
+
+	r.Nil(exclusive.Unlock())
+	r.Nil(shared.Lock())
+	r.Nil(shared.Unlock())
+}
+
 // We spawn another process while we hold the lock to make sure it cannot acquire it
 func TestLock(t *testing.T) {
 	r := require.New(t)
@@ -76,6 +180,11 @@ func TestLock(t *testing.T) {
 	if os.Getenv("SHOULD_FAIL_LOCK") == "YES" {
 		// This lock should fail since the other process owns it
 		r.NotNil(err)
+		lockedErr := &util.LockedError{}
+		r.True(errors.As(err, &lockedErr))
+		r.Equal(os.Getppid(), lockedErr.Holder.PID)
+		r.False(lockedErr.Holder.Stale)
+		r.Contains(err.Error(), fmt.Sprintf("held by pid %d", os.Getppid()))
 		return
 	}
 	r.Nil(err)
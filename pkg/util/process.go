@@ -0,0 +1,29 @@
+package util
+
+import (
+	"context"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// processCmdline returns the command line of pid or "" if we can't tell
+func processCmdline(pid int) string {
+	// linux
+	data, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+	if err == nil {
+		return strings.TrimSpace(strings.ReplaceAll(string(data), "\x00", " "))
+	}
+
+	// everything else with a ps
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "ps", "-o", "command=", "-p", strconv.Itoa(pid)).Output() // #nosec
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -2,12 +2,18 @@ package util
 
 import (
 	"fmt"
+	"runtime"
+	"runtime/debug"
 	"strconv"
+	"strings"
 
 	"github.com/blang/semver"
 	"github.com/pkg/errors"
 )
 
+// ModulePath is the go module blessclient is published as
+const ModulePath = "github.com/chanzuckerberg/blessclient"
+
 var (
 	// Version is the blessclient version
 	Version = "undefined"
@@ -17,8 +23,44 @@ var (
 	Release = "false"
 	// Dirty if git is dirty
 	Dirty = "true"
+	// BuildDate is when this version was built, in RFC3339
+	BuildDate = "undefined"
 )
 
+// BuildInfo describes this build, for bug reports
+type BuildInfo struct {
+	Version   string `json:"version"`
+	GitSha    string `json:"git_sha"`
+	Release   bool   `json:"release"`
+	Dirty     bool   `json:"dirty"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	BuildDate string `json:"build_date"`
+}
+
+// GetBuildInfo returns the build info of this binary
+func GetBuildInfo() (*BuildInfo, error) {
+	release, e := strconv.ParseBool(Release)
+	if e != nil {
+		return nil, errors.Wrapf(e, "unable to parse version release field %s", Release)
+	}
+	dirty, e := strconv.ParseBool(Dirty)
+	if e != nil {
+		return nil, errors.Wrapf(e, "unable to parse version dirty field %s", Dirty)
+	}
+	return &BuildInfo{
+		Version:   Version,
+		GitSha:    GitSha,
+		Release:   release,
+		Dirty:     dirty,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		BuildDate: BuildDate,
+	}, nil
+}
+
 // VersionString returns the version string
 func VersionString() (string, error) {
 	release, e := strconv.ParseBool(Release)
@@ -32,6 +74,49 @@ func VersionString() (string, error) {
 	return versionString(Version, GitSha, release, dirty), nil
 }
 
+// IsRelease returns true if this is a release build
+func IsRelease() bool {
+	release, e := strconv.ParseBool(Release)
+	return e == nil && release
+}
+
+// ModuleVersion returns the version of the blessclient module this program was built with.
+// Programs using blessclient as a library don't set Version, but go records which release
+// they depend on. ok is false for anything but a tagged release, eg a local checkout.
+func ModuleVersion() (version string, ok bool) {
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "", false
+	}
+	return moduleVersion(buildInfo)
+}
+
+func moduleVersion(buildInfo *debug.BuildInfo) (string, bool) {
+	module := &buildInfo.Main
+	if module.Path != ModulePath {
+		module = nil
+		for _, dep := range buildInfo.Deps {
+			if dep.Path == ModulePath {
+				module = dep
+				break
+			}
+		}
+	}
+	if module == nil {
+		return "", false
+	}
+	if module.Replace != nil {
+		module = module.Replace
+	}
+
+	v, err := semver.ParseTolerant(module.Version)
+	// pseudo versions are prereleases, so are untagged commits
+	if err != nil || len(v.Pre) > 0 || len(v.Build) > 0 {
+		return "", false
+	}
+	return strings.TrimPrefix(module.Version, "v"), true
+}
+
 // VersionCacheKey returns a key to version the cache
 func VersionCacheKey() string {
 	versionString, e := VersionString()
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleVersion(t *testing.T) {
+	r := require.New(t)
+
+	dep := func(version string) *debug.BuildInfo {
+		return &debug.BuildInfo{
+			Main: debug.Module{Path: "example.com/tool", Version: "(devel)"},
+			Deps: []*debug.Module{{Path: ModulePath, Version: version}},
+		}
+	}
+
+	version, ok := moduleVersion(dep("v1.2.3"))
+	r.True(ok)
+	r.Equal("1.2.3", version)
+
+	// untagged commits and local checkouts
+	for _, v := range []string{"v0.0.0-20240101000000-abcdef123456", "v1.2.4-0.20240101000000-abcdef123456", "(devel)", ""} {
+		_, ok = moduleVersion(dep(v))
+		r.False(ok, v)
+	}
+
+	// a replace wins
+	buildInfo := dep("v1.2.3")
+	buildInfo.Deps[0].Replace = &debug.Module{Path: "../blessclient"}
+	_, ok = moduleVersion(buildInfo)
+	r.False(ok)
+
+	// built from the module itself
+	version, ok = moduleVersion(&debug.BuildInfo{Main: debug.Module{Path: ModulePath, Version: "v1.3.0"}})
+	r.True(ok)
+	r.Equal("1.3.0", version)
+	_, ok = moduleVersion(&debug.BuildInfo{Main: debug.Module{Path: ModulePath, Version: "v1.3.0+dirty"}})
+	r.False(ok)
+
+	// not using blessclient at all
+	_, ok = moduleVersion(&debug.BuildInfo{Main: debug.Module{Path: "example.com/tool"}})
+	r.False(ok)
+}
@@ -1,6 +1,7 @@
 package util_test
 
 import (
+	"runtime"
 	"testing"
 
 	"github.com/chanzuckerberg/blessclient/pkg/util"
@@ -107,3 +108,21 @@ func TestVersionCacheKeyDirty(t *testing.T) {
 	s := util.VersionCacheKey()
 	r.Equal("1.1.1", s)
 }
+
+func TestGetBuildInfo(t *testing.T) {
+	oldVal := util.Release
+	defer func() {
+		util.Release = oldVal
+	}()
+	r := require.New(t)
+
+	info, err := util.GetBuildInfo()
+	r.Nil(err)
+	r.Equal(util.Version, info.Version)
+	r.Equal(runtime.GOOS, info.OS)
+	r.Equal(runtime.Version(), info.GoVersion)
+
+	util.Release = "some random value"
+	_, err = util.GetBuildInfo()
+	r.NotNil(err)
+}
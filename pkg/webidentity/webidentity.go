@@ -0,0 +1,141 @@
+// Package webidentity reads oidc tokens from non-interactive sources
+// such as CI providers and workload identity files.
+package webidentity
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultKubernetesTokenPath is where kubernetes mounts the service account token
+	DefaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+	envGithubActionsRequestURL   = "ACTIONS_ID_TOKEN_REQUEST_URL"
+	envGithubActionsRequestToken = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
+)
+
+// FromFile reads a token from a file
+func FromFile(tokenPath string) (string, error) {
+	expandedPath, err := homedir.Expand(tokenPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not expand %s", tokenPath)
+	}
+
+	b, err := ioutil.ReadFile(expandedPath) // #nosec
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read token from %s", tokenPath)
+	}
+
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", errors.Errorf("token file %s is empty", tokenPath)
+	}
+	return token, nil
+}
+
+// FromEnv reads a token from the environment variable name
+func FromEnv(name string) (string, error) {
+	token := strings.TrimSpace(os.Getenv(name))
+	if token == "" {
+		return "", errors.Errorf("environment variable %s is not set", name)
+	}
+	return token, nil
+}
+
+// Options select where Fetch reads a token from
+type Options struct {
+	// TokenFile is a file containing the token
+	TokenFile string
+	// TokenEnv is an environment variable containing the token
+	TokenEnv string
+	// Audience to request GitHub Actions tokens for
+	Audience string
+
+	HTTPClient *http.Client
+}
+
+// Fetch returns a token from the first available source in opts:
+// TokenFile, then TokenEnv, then the GitHub Actions token endpoint.
+func Fetch(ctx context.Context, opts Options) (string, error) {
+	switch {
+	case opts.TokenFile != "":
+		return FromFile(opts.TokenFile)
+	case opts.TokenEnv != "" && os.Getenv(opts.TokenEnv) != "":
+		return FromEnv(opts.TokenEnv)
+	case InGithubActions():
+		return FromGithubActions(ctx, opts.HTTPClient, opts.Audience)
+	default:
+		return "", errors.New("no oidc token available: provide a token file, a token environment variable or run in GitHub Actions")
+	}
+}
+
+// InGithubActions returns true if we can request GitHub Actions oidc tokens
+func InGithubActions() bool {
+	return os.Getenv(envGithubActionsRequestURL) != "" && os.Getenv(envGithubActionsRequestToken) != ""
+}
+
+type githubActionsTokenResponse struct {
+	Value string `json:"value"`
+}
+
+// FromGithubActions requests an oidc token for audience from the GitHub Actions token endpoint.
+// The job needs the `id-token: write` permission.
+func FromGithubActions(ctx context.Context, httpClient *http.Client, audience string) (string, error) {
+	requestURL := os.Getenv(envGithubActionsRequestURL)
+	requestToken := os.Getenv(envGithubActionsRequestToken)
+	if requestURL == "" || requestToken == "" {
+		return "", errors.Errorf(
+			"%s and %s must be set, does this job have the id-token: write permission?",
+			envGithubActionsRequestURL,
+			envGithubActionsRequestToken)
+	}
+
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not parse %s", envGithubActionsRequestURL)
+	}
+	if audience != "" {
+		q := u.Query()
+		q.Set("audience", audience)
+		u.RawQuery = q.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", errors.Wrap(err, "could not create github actions token request")
+	}
+	req.Header.Set("Authorization", "bearer "+requestToken)
+	req.Header.Set("Accept", "application/json")
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "could not request github actions token")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("github actions token endpoint returned %s", resp.Status)
+	}
+
+	tokenResponse := &githubActionsTokenResponse{}
+	err = json.NewDecoder(resp.Body).Decode(tokenResponse)
+	if err != nil {
+		return "", errors.Wrap(err, "could not json decode github actions token")
+	}
+	if tokenResponse.Value == "" {
+		return "", errors.New("github actions token endpoint returned an empty token")
+	}
+	return tokenResponse.Value, nil
+}
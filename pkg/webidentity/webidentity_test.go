@@ -0,0 +1,92 @@
+package webidentity_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/chanzuckerberg/blessclient/pkg/webidentity"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromFile(t *testing.T) {
+	r := require.New(t)
+
+	f, err := ioutil.TempFile("", "blessclient-token")
+	r.NoError(err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString("  my-token\n")
+	r.NoError(err)
+	r.NoError(f.Close())
+
+	token, err := webidentity.FromFile(f.Name())
+	r.NoError(err)
+	r.Equal("my-token", token)
+
+	_, err = webidentity.FromFile("notfoundnotfoundnotfound")
+	r.Error(err)
+}
+
+func TestFromGithubActions(t *testing.T) {
+	r := require.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "bearer request-token" {
+			http.Error(w, "bad token", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"value": "id-token-for-%s"}`, req.URL.Query().Get("audience"))
+	}))
+	defer server.Close()
+
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", server.URL+"/token?api-version=2.0")
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "request-token")
+	r.True(webidentity.InGithubActions())
+
+	token, err := webidentity.FromGithubActions(context.Background(), server.Client(), "bless")
+	r.NoError(err)
+	r.Equal("id-token-for-bless", token)
+
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_TOKEN", "wrong")
+	_, err = webidentity.FromGithubActions(context.Background(), server.Client(), "bless")
+	r.Error(err)
+	r.Contains(err.Error(), "401")
+}
+
+func TestFromGithubActionsNotConfigured(t *testing.T) {
+	r := require.New(t)
+
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+	r.False(webidentity.InGithubActions())
+
+	_, err := webidentity.FromGithubActions(context.Background(), nil, "")
+	r.Error(err)
+	r.Contains(err.Error(), "id-token: write")
+}
+
+func TestFetch(t *testing.T) {
+	r := require.New(t)
+	t.Setenv("ACTIONS_ID_TOKEN_REQUEST_URL", "")
+	t.Setenv("BLESSCLIENT_TEST_TOKEN", "env-token")
+
+	token, err := webidentity.Fetch(context.Background(), webidentity.Options{TokenEnv: "BLESSCLIENT_TEST_TOKEN"})
+	r.NoError(err)
+	r.Equal("env-token", token)
+
+	// the token file wins over the environment
+	_, err = webidentity.Fetch(context.Background(), webidentity.Options{
+		TokenFile: "notfoundnotfoundnotfound",
+		TokenEnv:  "BLESSCLIENT_TEST_TOKEN",
+	})
+	r.Error(err)
+	r.Contains(err.Error(), "notfoundnotfoundnotfound")
+
+	_, err = webidentity.Fetch(context.Background(), webidentity.Options{TokenEnv: "BLESSCLIENT_TEST_UNSET"})
+	r.Error(err)
+	r.Contains(err.Error(), "no oidc token available")
+}